@@ -0,0 +1,227 @@
+package game
+
+import "experiments/experiments/RPG/pathfind"
+
+type AIState int
+
+const (
+	StateIdle AIState = iota
+	StateWander
+	StatePatrol
+	StateChase
+	StateFlee
+	StateKeepDistance
+)
+
+func (s AIState) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateWander:
+		return "wander"
+	case StatePatrol:
+		return "patrol"
+	case StateChase:
+		return "chase"
+	case StateFlee:
+		return "flee"
+	case StateKeepDistance:
+		return "keep distance"
+	default:
+		return "unknown"
+	}
+}
+
+type ActionKind int
+
+const (
+	ActWait ActionKind = iota
+	// ActMove steps onto To; stepping onto a player is a melee attack.
+	ActMove
+	// ActShoot attacks the player at To from a distance, no counter-attack.
+	ActShoot
+)
+
+type Action struct {
+	Kind ActionKind
+	To   Position
+}
+
+// AI decides what a monster does with its next action.
+type AI interface {
+	Act(m *Monster, level *Level) Action
+}
+
+// StateMachine is the stock AI. While the monster hasn't noticed the player
+// it is Unaware (idle, wander or patrol); once it has, it is Aware (chase or
+// keep distance). Below FleeBelow of its MaxHitpoints it flees instead.
+type StateMachine struct {
+	Unaware    AIState
+	Aware      AIState
+	FleeBelow  float64
+	SightRange int
+	// for StateKeepDistance: the preferred gap to the player and the
+	// distance it shoots from
+	KeepDistance int
+	Range        int
+}
+
+// AIProfiles are the AIs monster definitions can refer to by name.
+var AIProfiles = map[string]AI{
+	"chaser":    StateMachine{Unaware: StateIdle, Aware: StateChase, SightRange: SightRadius},
+	"wanderer":  StateMachine{Unaware: StateWander, Aware: StateChase, FleeBelow: 0.25, SightRange: SightRadius},
+	"patroller": StateMachine{Unaware: StatePatrol, Aware: StateChase, SightRange: SightRadius},
+	"archer":    StateMachine{Unaware: StateIdle, Aware: StateKeepDistance, FleeBelow: 0.3, SightRange: SightRadius, KeepDistance: 3, Range: 5},
+	"coward":    StateMachine{Unaware: StateWander, Aware: StateFlee, SightRange: SightRadius},
+}
+
+const DefaultAI = "chaser"
+
+func (m *Monster) ai() AI {
+	if ai, ok := AIProfiles[m.AI]; ok {
+		return ai
+	}
+	return AIProfiles[DefaultAI]
+}
+
+// distance is how many steps apart a and b are, around nothing.
+func (level *Level) distance(a, b Position) int {
+	var dx, dy = a.X - b.X, a.Y - b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if !level.diagonal {
+		return dx + dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+// sees is true when the nearest player is in range and in sight from
+// where the monster stands now. level.Visible won't do: it is the party's
+// view as of the last player's turn, and doors may have opened and
+// monsters moved since.
+func (sm StateMachine) sees(m *Monster, level *Level) bool {
+	var player = level.nearestPlayer(m.Position).Position
+	return level.distance(m.Position, player) <= sm.SightRange && level.FOV(m.Position, sm.SightRange)[player]
+}
+
+func (sm StateMachine) think(m *Monster, level *Level) AIState {
+	if sm.sees(m, level) {
+		m.Noticed = true
+		m.LastSeen = level.nearestPlayer(m.Position).Position
+	} else if m.Noticed && m.Position == m.LastSeen {
+		// got to where the player was and they're gone
+		m.Noticed = false
+	}
+	switch {
+	case !m.Noticed:
+		return sm.Unaware
+	case sm.FleeBelow > 0 && float64(m.Hitpoints) < sm.FleeBelow*float64(m.MaxHitpoints):
+		return StateFlee
+	default:
+		return sm.Aware
+	}
+}
+
+func (sm StateMachine) Act(m *Monster, level *Level) Action {
+	m.State = sm.think(m, level)
+	switch m.State {
+	case StateWander:
+		return m.wander(level)
+	case StatePatrol:
+		return m.patrol(level)
+	case StateChase:
+		return m.approach(level, m.LastSeen)
+	case StateFlee:
+		return m.flee(level)
+	case StateKeepDistance:
+		var (
+			player = level.nearestPlayer(m.Position).Position
+			dist   = level.distance(m.Position, player)
+		)
+		switch {
+		case !sm.sees(m, level):
+			return m.approach(level, m.LastSeen)
+		case dist < sm.KeepDistance:
+			return m.flee(level)
+		case dist <= sm.Range:
+			return Action{ActShoot, player}
+		default:
+			return m.approach(level, player)
+		}
+	}
+	return Action{Kind: ActWait}
+}
+
+func (m *Monster) approach(level *Level, goal Position) Action {
+	var path = level.astar(m.Position, goal)
+	// path[0] is where the monster stands
+	if len(path) > 1 {
+		return Action{ActMove, path[1]}
+	}
+	return Action{Kind: ActWait}
+}
+
+// freeNeighbors are the walkable tiles next to m that no other monster or
+// player holds.
+func (m *Monster) freeNeighbors(level *Level) []Position {
+	var free = make([]Position, 0, 8)
+	for _, pos := range getNeighbors(level, m.Position) {
+		if _, taken := level.Monsters[pos]; !taken && level.playerAt(pos) == nil {
+			free = append(free, pos)
+		}
+	}
+	return free
+}
+
+// flee steps to the free neighbor farthest from the nearest player by
+// path, so that the monster runs down corridors rather than into dead ends.
+func (m *Monster) flee(level *Level) Action {
+	var (
+		player = level.nearestPlayer(m.Position).Position
+		best   = m.Position
+		dist   = pathfind.Dijkstra(level.grid(canWalk), pathfind.Point(player))
+	)
+	for _, pos := range m.freeNeighbors(level) {
+		if dist[pathfind.Point(pos)] > dist[pathfind.Point(best)] {
+			best = pos
+		}
+	}
+	if best == m.Position {
+		// cornered
+		if level.distance(m.Position, player) == 1 {
+			return Action{ActMove, player}
+		}
+		return Action{Kind: ActWait}
+	}
+	return Action{ActMove, best}
+}
+
+// wander takes a random step now and then. The roll is derived from the
+// turn and position rather than a shared generator so that a turn replays
+// the same way regardless of what else happened.
+func (m *Monster) wander(level *Level) Action {
+	var free = m.freeNeighbors(level)
+	var roll = int(uint32(m.X*73856093^m.Y*19349663^level.turn*83492791) % 8)
+	if roll >= len(free) {
+		return Action{Kind: ActWait}
+	}
+	return Action{ActMove, free[roll]}
+}
+
+// patrol walks Route in a loop, going home first if knocked off it.
+func (m *Monster) patrol(level *Level) Action {
+	if len(m.Route) == 0 {
+		return m.approach(level, m.Home)
+	}
+	if m.Position == m.Route[m.RouteIndex%len(m.Route)] {
+		m.RouteIndex = (m.RouteIndex + 1) % len(m.Route)
+	}
+	return m.approach(level, m.Route[m.RouteIndex%len(m.Route)])
+}
@@ -0,0 +1,86 @@
+package game
+
+import "testing"
+
+func TestStateMachine(t *testing.T) {
+	var tests = []struct {
+		name    string
+		rows    []string
+		ai      string
+		monster Position
+		// hitpoints is what the monster is left with, 0 leaves it unhurt
+		hitpoints int
+		state     AIState
+		action    Action
+	}{
+		{"chase", []string{"#######", "#@..S.#", "#######"}, "chaser", Position{4, 1}, 0,
+			StateChase, Action{ActMove, Position{3, 1}}},
+		{"attack", []string{"#####", "#@S.#", "#####"}, "chaser", Position{2, 1}, 0,
+			StateChase, Action{ActMove, Position{1, 1}}},
+		{"behind a wall", []string{"#######", "#@.#S.#", "#######"}, "chaser", Position{4, 1}, 0,
+			StateIdle, Action{Kind: ActWait}},
+		{"behind a door", []string{"#######", "#@.|S.#", "#######"}, "chaser", Position{4, 1}, 0,
+			StateIdle, Action{Kind: ActWait}},
+		{"out of range", []string{"#############", "#@.........S#", "#############"}, "chaser", Position{11, 1}, 0,
+			StateIdle, Action{Kind: ActWait}},
+		{"flee", []string{"#######", "#@.R..#", "#######"}, "wanderer", Position{3, 1}, 1,
+			StateFlee, Action{ActMove, Position{4, 1}}},
+		{"not hurt enough to flee", []string{"#######", "#@.R..#", "#######"}, "wanderer", Position{3, 1}, 400,
+			StateChase, Action{ActMove, Position{2, 1}}},
+		{"cornered", []string{"####", "#@R#", "####"}, "coward", Position{2, 1}, 0,
+			StateFlee, Action{ActMove, Position{1, 1}}},
+		{"shoot", []string{"########", "#@...g.#", "########"}, "archer", Position{5, 1}, 0,
+			StateKeepDistance, Action{ActShoot, Position{1, 1}}},
+		{"keep distance", []string{"########", "#@.g...#", "########"}, "archer", Position{3, 1}, 0,
+			StateKeepDistance, Action{ActMove, Position{4, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var level = testWorld(t, test.rows...).Level()
+			var m = level.Monsters[test.monster]
+			if m == nil {
+				t.Fatalf("no monster at %v", test.monster)
+			}
+			if test.hitpoints > 0 {
+				m.Hitpoints = test.hitpoints
+			}
+			var action = AIProfiles[test.ai].Act(m, level)
+			if m.State != test.state || action != test.action {
+				t.Errorf("%v %+v, want %v %+v", m.State, action, test.state, test.action)
+			}
+		})
+	}
+}
+
+// TestSight checks that a monster goes by what it sees from where it
+// stands, not by what the party saw last.
+func TestSight(t *testing.T) {
+	var level = testWorld(t, "#######", "#@.#S.#", "#######").Level()
+	var m = level.Monsters[Position{4, 1}]
+	level.Visible[m.Position] = true
+	if state := AIProfiles["chaser"].(StateMachine).think(m, level); state != StateIdle {
+		t.Errorf("sees through the wall from behind it: %v", state)
+	}
+
+	level = testWorld(t, "#######", "#@..S.#", "#######").Level()
+	m = level.Monsters[Position{4, 1}]
+	level.Visible = map[Position]bool{}
+	if state := AIProfiles["chaser"].(StateMachine).think(m, level); state != StateChase {
+		t.Errorf("doesn't see the player in the open: %v", state)
+	}
+}
+
+// TestLoseTrack has a monster follow the player to where it last saw them,
+// then give up.
+func TestLoseTrack(t *testing.T) {
+	var level = testWorld(t, "#######", "#@.#S.#", "#######").Level()
+	var m = level.Monsters[Position{4, 1}]
+	m.Noticed, m.LastSeen = true, Position{5, 1}
+	if action := AIProfiles["chaser"].Act(m, level); m.State != StateChase || action != (Action{ActMove, Position{5, 1}}) {
+		t.Errorf("%v %+v, want it to head for where it saw the player", m.State, action)
+	}
+	m.Move(Position{5, 1}, level)
+	if AIProfiles["chaser"].Act(m, level); m.State != StateIdle || m.Noticed {
+		t.Errorf("%v, noticed %v, want it to give up", m.State, m.Noticed)
+	}
+}
@@ -0,0 +1,303 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bestiary files describe the monster kinds, one section per monster:
+//
+//	rpgbestiary 1
+//
+//	# lines starting with # are comments
+//	[Goblin]
+//	glyph: g
+//	hitpoints: 30
+//	strength: 4
+//	speed: 1
+//	accuracy: 5
+//	evasion: 5
+//	crit: 0
+//	ai: chaser
+//	loot: Health Potion 50%, Sword 10%
+//	xp: 15
+//	sprite: 5,12,1
+//
+// glyph and hitpoints are required, speed defaults to 1 and ai to DefaultAI.
+// A loot entry without a chance always drops. sprite is "x,y,variations" in
+// the tile atlas, used unless the atlas has a sprite for the monster.
+const BestiaryVersion = 1
+
+const bestiaryMagic = "rpgbestiary"
+
+// LootDrop is a loot table row: Item is left behind with Chance percent.
+type LootDrop struct {
+	Item   string `json:"item"`
+	Chance int    `json:"chance"`
+}
+
+// UnmarshalJSON also takes a plain item name, which is how saves from
+// before loot tables stored loot.
+func (d *LootDrop) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*d = LootDrop{name, 100}
+		return nil
+	}
+	type drop LootDrop
+	return json.Unmarshal(data, (*drop)(d))
+}
+
+type Sprite struct {
+	X, Y       int
+	Variations int
+}
+
+type MonsterDef struct {
+	Name       string
+	Glyph      rune
+	Hitpoints  int
+	Strength   int
+	Speed      float64
+	Accuracy   int
+	Evasion    int
+	CritChance int
+	AI         string
+	Loot       []LootDrop
+	XP         int
+	Sprite     Sprite
+}
+
+// New spawns a monster of this kind at pos.
+func (def *MonsterDef) New(pos Position) *Monster {
+	return &Monster{Character: Character{
+		Entity: Entity{
+			Position: pos,
+			Name:     def.Name,
+			Rune:     def.Glyph,
+		},
+		Hitpoints:    def.Hitpoints,
+		MaxHitpoints: def.Hitpoints,
+		Strength:     def.Strength,
+		Speed:        def.Speed,
+		Accuracy:     def.Accuracy,
+		Evasion:      def.Evasion,
+		CritChance:   def.CritChance,
+	}, AI: def.AI, Home: pos, Loot: append([]LootDrop(nil), def.Loot...), XP: def.XP}
+}
+
+// Bestiary is the set of monster kinds maps and generators can spawn, by
+// name. Each Game has its own.
+type Bestiary map[string]*MonsterDef
+
+// NewBestiary makes a Bestiary of defs.
+func NewBestiary(defs []*MonsterDef) Bestiary {
+	var bestiary = make(Bestiary, len(defs))
+	for _, def := range defs {
+		bestiary[def.Name] = def
+	}
+	return bestiary
+}
+
+// StockBestiary is maps/bestiary.txt as compiled into Files.
+func StockBestiary() (Bestiary, error) {
+	file, err := Files.Open("maps/bestiary.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readBestiary(file, "maps/bestiary.txt")
+}
+
+// LoadBestiary reads a bestiary file through OpenAsset.
+func LoadBestiary(path string) (Bestiary, error) {
+	file, err := OpenAsset(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readBestiary(file, path)
+}
+
+func readBestiary(r io.Reader, path string) (Bestiary, error) {
+	defs, err := ParseBestiary(r)
+	if err != nil {
+		if bestiaryErr, ok := err.(*MapError); ok {
+			bestiaryErr.File = path
+		}
+		return nil, err
+	}
+	return NewBestiary(defs), nil
+}
+
+// NewMonster spawns the named kind at pos, nil if there's no such kind.
+func (bestiary Bestiary) NewMonster(name string, pos Position) *Monster {
+	if def, ok := bestiary[name]; ok {
+		return def.New(pos)
+	}
+	return nil
+}
+
+// Defs lists the monster kinds by name.
+func (bestiary Bestiary) Defs() []*MonsterDef {
+	var defs = make([]*MonsterDef, 0, len(bestiary))
+	for _, def := range bestiary {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// ParseBestiary reads and checks a bestiary. Errors are *MapError with
+// File set to "bestiary".
+func ParseBestiary(r io.Reader) ([]*MonsterDef, error) {
+	var (
+		lines  = NewLineScanner(r, "bestiary")
+		defs   []*MonsterDef
+		def    *MonsterDef
+		start  = 0 // line of def's section header
+		glyphs = make(map[rune]string)
+	)
+	var fail = func(line, column int, format string, args ...interface{}) error {
+		return &MapError{File: "bestiary", Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+	}
+	var finish = func() error {
+		if def == nil {
+			return nil
+		}
+		if def.Glyph == 0 {
+			return fail(start, 0, "monster %q has no glyph", def.Name)
+		}
+		if def.Hitpoints <= 0 {
+			return fail(start, 0, "monster %q needs hitpoints above 0", def.Name)
+		}
+		defs = append(defs, def)
+		return nil
+	}
+
+	if !lines.Scan() && lines.Err() != nil {
+		return nil, lines.Err()
+	}
+	version, err := lines.Version(bestiaryMagic, BestiaryVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return nil, lines.Errorf(0, "missing %q header", bestiaryMagic)
+	}
+	for lines.Scan() {
+		if lines.Blank() {
+			continue
+		}
+		if name, ok := lines.Section(); ok {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if name == "" {
+				return nil, lines.Errorf(0, "monster has no name")
+			}
+			for _, other := range defs {
+				if other.Name == name {
+					return nil, lines.Errorf(0, "monster %q is defined twice", name)
+				}
+			}
+			def, start = &MonsterDef{Name: name, Speed: 1, AI: DefaultAI}, lines.Line
+			continue
+		}
+
+		key, value, column, err := lines.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+		if def == nil {
+			return nil, lines.Errorf(0, "key outside of a [monster] section")
+		}
+		switch key {
+		case "glyph":
+			var runes = []rune(value)
+			if len(runes) != 1 {
+				return nil, lines.Errorf(column, "glyph must be a single character, got %q", value)
+			}
+			if _, taken := stockLegend()[runes[0]]; taken {
+				return nil, lines.Errorf(column, "glyph %q is already used by the map legend", runes[0])
+			}
+			if other, taken := glyphs[runes[0]]; taken {
+				return nil, lines.Errorf(column, "glyph %q is already used by %q", runes[0], other)
+			}
+			def.Glyph = runes[0]
+			glyphs[def.Glyph] = def.Name
+		case "hitpoints":
+			def.Hitpoints, err = strconv.Atoi(value)
+		case "strength":
+			def.Strength, err = strconv.Atoi(value)
+		case "accuracy":
+			def.Accuracy, err = strconv.Atoi(value)
+		case "evasion":
+			def.Evasion, err = strconv.Atoi(value)
+		case "crit":
+			def.CritChance, err = strconv.Atoi(value)
+		case "xp":
+			def.XP, err = strconv.Atoi(value)
+		case "speed":
+			def.Speed, err = strconv.ParseFloat(value, 64)
+			if err == nil && def.Speed <= 0 {
+				return nil, lines.Errorf(column, "speed must be above 0")
+			}
+		case "ai":
+			if _, ok := AIProfiles[value]; !ok {
+				return nil, lines.Errorf(column, "unknown ai profile %q", value)
+			}
+			def.AI = value
+		case "loot":
+			def.Loot, err = parseLoot(value)
+			if err != nil {
+				return nil, lines.Errorf(column, "%v", err)
+			}
+		case "sprite":
+			var s Sprite
+			if _, err := fmt.Sscanf(value, "%d,%d,%d", &s.X, &s.Y, &s.Variations); err != nil || s.Variations < 1 {
+				return nil, lines.Errorf(column, "bad sprite %q, expected x,y,variations", value)
+			}
+			def.Sprite = s
+		default:
+			return nil, lines.Errorf(0, "unknown key %q", key)
+		}
+		if err != nil {
+			return nil, lines.Errorf(column, "bad %s %q", key, value)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// parseLoot reads "Health Potion 50%, Sword".
+func parseLoot(value string) ([]LootDrop, error) {
+	var loot []LootDrop
+	for _, part := range strings.Split(value, ",") {
+		var (
+			drop   = LootDrop{strings.TrimSpace(part), 100}
+			fields = strings.Fields(drop.Item)
+		)
+		if n := len(fields); n > 1 && strings.HasSuffix(fields[n-1], "%") {
+			chance, err := strconv.Atoi(strings.TrimSuffix(fields[n-1], "%"))
+			if err != nil || chance < 1 || chance > 100 {
+				return nil, fmt.Errorf("bad drop chance %q", fields[n-1])
+			}
+			drop = LootDrop{strings.Join(fields[:n-1], " "), chance}
+		}
+		if _, ok := itemKinds[drop.Item]; !ok {
+			return nil, fmt.Errorf("unknown item %q", drop.Item)
+		}
+		loot = append(loot, drop)
+	}
+	return loot, nil
+}
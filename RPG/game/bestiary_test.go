@@ -0,0 +1,63 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStockBestiary(t *testing.T) {
+	var bestiary = stockBestiary(t)
+	var names []string
+	for _, def := range bestiary.Defs() {
+		names = append(names, def.Name)
+	}
+	if strings.Join(names, ",") != "Goblin,Rat,Spider" {
+		t.Errorf("stock monsters %v", names)
+	}
+	var goblin = bestiary.NewMonster("Goblin", Position{2, 3})
+	if goblin == nil || goblin.Rune != 'g' || goblin.Home != (Position{2, 3}) || len(goblin.Loot) != 2 || goblin.Loot[0] != (LootDrop{"Health Potion", 50}) {
+		t.Errorf("goblin %+v", goblin)
+	}
+	if bestiary.NewMonster("Dragon", Position{}) != nil {
+		t.Error("spawned a monster the bestiary doesn't have")
+	}
+}
+
+func TestParseBestiary(t *testing.T) {
+	var tests = []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"ok", "rpgbestiary 1\n# a comment\n[Bat]\nglyph: b\nhitpoints: 3\nspeed: 2\nloot: Sword 10%, Health Potion\n", ""},
+		{"no header", "[Bat]\n", `bestiary:1: missing "rpgbestiary" header`},
+		{"empty", "", `bestiary:1: missing "rpgbestiary" header`},
+		{"version", "rpgbestiary 2\n", "bestiary:1: unsupported bestiary version 2"},
+		{"outside", "rpgbestiary 1\nglyph: b\n", "bestiary:2: key outside of a [monster] section"},
+		{"no colon", "rpgbestiary 1\n[Bat]\nglyph b\n", `bestiary:3: expected "key: value"`},
+		{"no glyph", "rpgbestiary 1\n[Bat]\nhitpoints: 3\n", `bestiary:2: monster "Bat" has no glyph`},
+		{"no hitpoints", "rpgbestiary 1\n[Bat]\nglyph: b\n[Owl]\n", `bestiary:2: monster "Bat" needs hitpoints above 0`},
+		{"twice", "rpgbestiary 1\n[Bat]\nglyph: b\nhitpoints: 3\n[Bat]\n", `bestiary:5: monster "Bat" is defined twice`},
+		{"legend glyph", "rpgbestiary 1\n[Bat]\nglyph:  #\n", "bestiary:3:9: glyph '#' is already used by the map legend"},
+		{"glyph twice", "rpgbestiary 1\n[Bat]\nglyph: b\nhitpoints: 3\n[Owl]\nglyph: b\n", `bestiary:6:8: glyph 'b' is already used by "Bat"`},
+		{"bad number", "rpgbestiary 1\n[Bat]\nhitpoints: lots\n", `bestiary:3:12: bad hitpoints "lots"`},
+		{"bad chance", "rpgbestiary 1\n[Bat]\nloot: Sword 150%\n", `bestiary:3:7: bad drop chance "150%"`},
+		{"bad ai", "rpgbestiary 1\n[Bat]\nai: sleepy\n", `bestiary:3:5: unknown ai profile "sleepy"`},
+		{"unknown key", "rpgbestiary 1\n[Bat]\ncolor: red\n", `bestiary:3: unknown key "color"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defs, err := ParseBestiary(strings.NewReader(test.text))
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatal(err)
+			case test.err == "":
+				if len(defs) != 1 || defs[0].Speed != 2 || len(defs[0].Loot) != 2 || defs[0].AI != DefaultAI {
+					t.Errorf("parsed %+v", defs[0])
+				}
+			case err == nil || err.Error() != test.err:
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package game
+
+// RNG is the randomness combat needs. *rand.Rand satisfies it, but the
+// game uses SeededRNG so that its state can go into a save.
+type RNG interface {
+	Intn(n int) int
+}
+
+// SeededRNG is a splitmix64 generator. State is all there is to it.
+type SeededRNG struct {
+	State uint64
+}
+
+func NewRNG(seed int64) *SeededRNG {
+	return &SeededRNG{uint64(seed)}
+}
+
+func (r *SeededRNG) Uint64() uint64 {
+	r.State += 0x9e3779b97f4a7c15
+	var z = r.State
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (r *SeededRNG) Intn(n int) int {
+	if n <= 0 {
+		panic("game: invalid argument to Intn")
+	}
+	return int(r.Uint64() % uint64(n))
+}
+
+// CombatRules are the knobs of the resolver. Chances are in percent.
+type CombatRules struct {
+	HitChance    int `json:"hit_chance"`
+	MinHitChance int `json:"min_hit_chance"`
+	MaxHitChance int `json:"max_hit_chance"`
+	CritChance   int `json:"crit_chance"`
+	// CritMultiplier is in percent, 200 doubles the damage
+	CritMultiplier int `json:"crit_multiplier"`
+	// Counter lets a defender that survives a melee hit strike back
+	Counter bool `json:"counter"`
+}
+
+var DefaultRules = CombatRules{
+	HitChance:      85,
+	MinHitChance:   5,
+	MaxHitChance:   95,
+	CritChance:     5,
+	CritMultiplier: 200,
+	Counter:        true,
+}
+
+// Strike is the outcome of one blow.
+type Strike struct {
+	Hit       bool
+	Critical  bool
+	HitChance int
+	Roll      int
+	// Damage is what came off the defender's hitpoints,
+	// Blocked what the armor soaked up.
+	Damage  int
+	Blocked int
+}
+
+type CombatResult struct {
+	Strike  Strike
+	Counter *Strike
+}
+
+type Resolver struct {
+	Rules CombatRules
+	RNG   RNG
+}
+
+func NewResolver(rules CombatRules, rng RNG) *Resolver {
+	return &Resolver{rules, rng}
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Strike rolls to hit, for damage and for a critical, in that order,
+// and applies the damage to the defender.
+func (r *Resolver) Strike(attacker, defender Attackable) Strike {
+	var s Strike
+	s.HitChance = clamp(r.Rules.HitChance+attacker.GetAccuracy()-defender.GetEvasion(),
+		r.Rules.MinHitChance, r.Rules.MaxHitChance)
+	s.Roll = r.RNG.Intn(100)
+	if s.Roll >= s.HitChance {
+		return s
+	}
+	s.Hit = true
+
+	var lo, hi = attacker.GetDamageRange()
+	var dmg = lo
+	if hi > lo {
+		dmg += r.RNG.Intn(hi - lo + 1)
+	}
+	if r.RNG.Intn(100) < r.Rules.CritChance+attacker.GetCritChance() {
+		s.Critical = true
+		dmg = dmg * r.Rules.CritMultiplier / 100
+	}
+	s.Blocked = clamp(defender.GetDefense(), 0, dmg)
+	s.Damage = dmg - s.Blocked
+	defender.SetHitpoints(defender.GetHitpoints() - s.Damage)
+	return s
+}
+
+// Fight is a melee exchange: one strike, then a counter-strike if the
+// rules allow it and the defender is still standing.
+func (r *Resolver) Fight(attacker, defender Attackable) CombatResult {
+	var result = CombatResult{Strike: r.Strike(attacker, defender)}
+	if r.Rules.Counter && defender.GetHitpoints() > 0 {
+		var counter = r.Strike(defender, attacker)
+		result.Counter = &counter
+	}
+	return result
+}
+
+func (level *Level) resolver() *Resolver {
+	if level.combat == nil {
+		level.combat = NewResolver(DefaultRules, NewRNG(1))
+	}
+	return level.combat
+}
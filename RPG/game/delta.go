@@ -0,0 +1,170 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrDeltaBase = errors.New("game: delta doesn't follow the snapshot it's applied to")
+
+// Delta is a Snapshot written as what changed since the one before, so
+// that it can go over the network as JSON and be put back together with
+// Apply. The actors and items are few and always sent in full; the tiles,
+// all of them when Full, only those that changed otherwise; the events
+// only those that are new.
+type Delta struct {
+	Version int `json:"version"`
+	// Base is the Version it applies to, 0 when Full
+	Base     int     `json:"base,omitempty"`
+	Full     bool    `json:"full,omitempty"`
+	Depth    int     `json:"depth"`
+	Name     string  `json:"name"`
+	Turn     int     `json:"turn"`
+	GameOver bool    `json:"game_over,omitempty"`
+	Player   Player  `json:"player"`
+	Others   []Actor `json:"others,omitempty"`
+	Monsters []Actor `json:"monsters,omitempty"`
+	Items    []Item  `json:"items,omitempty"`
+	// Map are all the tiles when Full, Tiles those that changed if not
+	Map    [][]Tile    `json:"map,omitempty"`
+	Tiles  []deltaTile `json:"tiles,omitempty"`
+	Events []saveEvent `json:"events,omitempty"`
+}
+
+type deltaTile struct {
+	Position
+	Tile
+}
+
+// Delta is what changed from prev to s, prev may be nil.
+func (s *Snapshot) Delta(prev *Snapshot) (Delta, error) {
+	var (
+		diff = s.Diff(prev)
+		d    = Delta{
+			Version:  s.Version,
+			Full:     diff.Full,
+			Depth:    s.Depth,
+			Name:     s.Name,
+			Turn:     s.Turn,
+			GameOver: s.GameOver,
+			Player:   s.Player,
+			Others:   sortedActors(s.Others),
+			Monsters: sortedActors(s.Monsters),
+		}
+		events = diff.Events
+	)
+	var positions = make([]Position, 0, len(s.Items))
+	for pos := range s.Items {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positionLess(positions[i], positions[j]) })
+	for _, pos := range positions {
+		d.Items = append(d.Items, s.Items[pos]...)
+	}
+	if diff.Full {
+		d.Map = s.tiles
+		events = s.Events
+	} else {
+		d.Base = prev.Version
+		for _, pos := range diff.Tiles {
+			d.Tiles = append(d.Tiles, deltaTile{pos, s.Tile(pos)})
+		}
+	}
+	for _, e := range events {
+		var typ = eventType(e)
+		if typ == "" {
+			continue
+		}
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return d, err
+		}
+		d.Events = append(d.Events, saveEvent{typ, raw})
+	}
+	return d, nil
+}
+
+func sortedActors(actors map[Position]Actor) []Actor {
+	var sorted = make([]Actor, 0, len(actors))
+	for _, a := range actors {
+		sorted = append(sorted, a)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return positionLess(sorted[i].Position, sorted[j].Position) })
+	return sorted
+}
+
+// Apply puts the snapshot d was made from back together on top of the
+// one before it, prev may be nil if d is Full. Like the game's, the
+// snapshot shares the rows that didn't change with prev.
+func (d Delta) Apply(prev *Snapshot) (*Snapshot, error) {
+	var s = &Snapshot{
+		Version:  d.Version,
+		Depth:    d.Depth,
+		Name:     d.Name,
+		Turn:     d.Turn,
+		GameOver: d.GameOver,
+		Player:   d.Player,
+		Others:   make(map[Position]Actor, len(d.Others)),
+		Monsters: make(map[Position]Actor, len(d.Monsters)),
+		Items:    make(map[Position][]Item),
+	}
+	for _, a := range d.Others {
+		s.Others[a.Position] = a
+	}
+	for _, a := range d.Monsters {
+		s.Monsters[a.Position] = a
+	}
+	for _, item := range d.Items {
+		s.Items[item.Position] = append(s.Items[item.Position], item)
+	}
+
+	var events []Event
+	for _, e := range d.Events {
+		event, err := decodeEvent(e.Type, e.Event)
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			events = append(events, event)
+		}
+	}
+
+	if d.Full {
+		for y, row := range d.Map {
+			if len(row) != len(d.Map[0]) {
+				return nil, fmt.Errorf("game: delta row %d is %d tiles wide, not %d", y, len(row), len(d.Map[0]))
+			}
+		}
+		// a level of its own, only ever compared
+		s.level = &Level{Name: d.Name}
+		s.tiles = d.Map
+		s.Events = events
+		s.published = len(events)
+		return s, nil
+	}
+	if prev == nil || prev.Version != d.Base {
+		return nil, ErrDeltaBase
+	}
+	s.level = prev.level
+	s.tiles = make([][]Tile, len(prev.tiles))
+	copy(s.tiles, prev.tiles)
+	var copied = make(map[int]bool)
+	for _, t := range d.Tiles {
+		if !s.InRange(t.Position) {
+			return nil, fmt.Errorf("game: delta tile at %v is off the map", t.Position)
+		}
+		if !copied[t.Y] {
+			s.tiles[t.Y] = append([]Tile(nil), prev.tiles[t.Y]...)
+			copied[t.Y] = true
+		}
+		s.tiles[t.Y][t.X] = t.Tile
+	}
+	s.Events = append(prev.Events[:len(prev.Events):len(prev.Events)], events...)
+	if n := len(s.Events) - eventHistory; n > 0 {
+		s.Events = s.Events[n:]
+	}
+	s.published = prev.published + len(events)
+	return s, nil
+}
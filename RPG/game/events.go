@@ -0,0 +1,341 @@
+package game
+
+import "fmt"
+
+// Participant identifies a character taking part in an event.
+type Participant struct {
+	Name   string
+	Player bool
+}
+
+func participant(c *Character, level *Level) Participant {
+	return Participant{c.Name, level.isPlayer(c)}
+}
+
+type EventHeader struct {
+	Turn     int
+	Position Position
+}
+
+func (h EventHeader) Header() EventHeader {
+	return h
+}
+
+// Event is anything published to a Level's EventLog:
+// AttackEvent, DamageEvent, DeathEvent, DoorOpenedEvent, ItemEvent,
+// MoveEvent, StairsEvent, ExperienceEvent, LevelUpEvent or NoticeEvent.
+type Event interface {
+	Header() EventHeader
+}
+
+type MoveEvent struct {
+	EventHeader
+	Actor Participant
+	From  Position
+}
+
+// AttackEvent is published for every blow, hit or miss, at the target's
+// position. From is where the attacker stood, Power its attack power,
+// HitChance and Roll the to-hit roll.
+type AttackEvent struct {
+	EventHeader
+	Actor     Participant
+	Target    Participant
+	From      Position
+	Power     int
+	Hit       bool
+	Critical  bool
+	HitChance int
+	Roll      int
+}
+
+type DamageEvent struct {
+	EventHeader
+	Actor     Participant
+	Target    Participant
+	Amount    int
+	Blocked   int
+	Hitpoints int
+}
+
+type DeathEvent struct {
+	EventHeader
+	Actor  Participant
+	Target Participant
+}
+
+type DoorOpenedEvent struct {
+	EventHeader
+	Actor Participant
+}
+
+// StairsEvent is published on both levels when the player takes stairs.
+// From and To are indexes into World.Levels.
+type StairsEvent struct {
+	EventHeader
+	Actor    Participant
+	From, To int
+}
+
+type ItemAction int
+
+const (
+	PickedUp ItemAction = iota
+	Dropped
+	Drank
+	Equipped
+)
+
+type ItemEvent struct {
+	EventHeader
+	Actor  Participant
+	Action ItemAction
+	Item   string
+}
+
+type ExperienceEvent struct {
+	EventHeader
+	Actor  Participant
+	Amount int
+	Total  int
+}
+
+type LevelUpEvent struct {
+	EventHeader
+	Actor Participant
+	Level int
+}
+
+// NoticeEvent tells a player why their input did nothing, a travel that
+// couldn't start say. Text is what the screen shows.
+type NoticeEvent struct {
+	EventHeader
+	Actor Participant
+	Text  string
+}
+
+// FormatEvent returns the on-screen text for an event,
+// or "" for events that aren't shown.
+func FormatEvent(e Event) string {
+	switch e := e.(type) {
+	case AttackEvent:
+		switch {
+		case !e.Hit && e.Actor.Player:
+			return fmt.Sprintf("Player Missed %s", e.Target.Name)
+		case !e.Hit:
+			return fmt.Sprintf("%s Missed Player", e.Actor.Name)
+		case e.Critical && e.Actor.Player:
+			return fmt.Sprintf("Player Critically Hit %s", e.Target.Name)
+		case e.Critical:
+			return fmt.Sprintf("%s Critically Hits %d Player !", e.Actor.Name, e.Power)
+		case e.Actor.Player:
+			return "Player Attacked Monster"
+		}
+		return fmt.Sprintf("%s Attacks %d Player !", e.Actor.Name, e.Power)
+	case DeathEvent:
+		return fmt.Sprintf("%s Died", e.Target.Name)
+	case DoorOpenedEvent:
+		return fmt.Sprintf("%s Opened Door", e.Actor.Name)
+	case ItemEvent:
+		var verb = [...]string{"Picked Up", "Dropped", "Drank", "Equipped"}[e.Action]
+		return fmt.Sprintf("%s %s %s", e.Actor.Name, verb, e.Item)
+	case ExperienceEvent:
+		return fmt.Sprintf("%s Gained %d XP", e.Actor.Name, e.Amount)
+	case LevelUpEvent:
+		return fmt.Sprintf("%s Reached Level %d !", e.Actor.Name, e.Level)
+	case StairsEvent:
+		if e.To > e.From {
+			return fmt.Sprintf("%s Went Down To Level %d", e.Actor.Name, e.To+1)
+		}
+		return fmt.Sprintf("%s Went Up To Level %d", e.Actor.Name, e.To+1)
+	case NoticeEvent:
+		return e.Text
+	default:
+		return ""
+	}
+}
+
+type subscriber struct {
+	id int
+	fn func(Event)
+}
+
+// EventLog keeps the last capacity events and passes every
+// published event to its subscribers in subscription order.
+type EventLog struct {
+	events      []Event
+	start       int
+	size        int
+	subscribers []subscriber
+	nextID      int
+	published   int // ever, for snapshots to tell which events are new
+}
+
+func NewEventLog(capacity int) *EventLog {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &EventLog{events: make([]Event, capacity)}
+}
+
+func (log *EventLog) Publish(e Event) {
+	var capacity = len(log.events)
+	log.published++
+	if log.size < capacity {
+		log.events[(log.start+log.size)%capacity] = e
+		log.size++
+	} else {
+		log.events[log.start] = e
+		log.start = (log.start + 1) % capacity
+	}
+	for _, s := range log.subscribers {
+		s.fn(e)
+	}
+}
+
+// Subscribe registers fn for every future event and returns
+// a function that removes it again. Removing a subscriber while an event
+// is published leaves the others to get that event as they would.
+func (log *EventLog) Subscribe(fn func(Event)) func() {
+	var id = log.nextID
+	log.nextID++
+	log.subscribers = append(log.subscribers, subscriber{id, fn})
+	return func() {
+		for i, s := range log.subscribers {
+			if s.id == id {
+				// a new array, Publish may be ranging over the old one
+				log.subscribers = append(log.subscribers[:i:i], log.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (log *EventLog) Len() int {
+	return log.size
+}
+
+func (log *EventLog) Cap() int {
+	return len(log.events)
+}
+
+// All returns the kept history, oldest first.
+func (log *EventLog) All() []Event {
+	var result = make([]Event, log.size)
+	for i := range result {
+		result[i] = log.events[(log.start+i)%len(log.events)]
+	}
+	return result
+}
+
+// Recent returns up to the last n events, oldest first.
+func (log *EventLog) Recent(n int) []Event {
+	var all = log.All()
+	if n < 0 {
+		n = 0
+	}
+	if n < len(all) {
+		all = all[len(all)-n:]
+	}
+	return all
+}
+
+// Since returns the kept events from turn onwards.
+func (log *EventLog) Since(turn int) []Event {
+	return log.Filter(func(e Event) bool {
+		return e.Header().Turn >= turn
+	})
+}
+
+func (log *EventLog) Filter(keep func(Event) bool) []Event {
+	var result = make([]Event, 0)
+	for _, e := range log.All() {
+		if keep(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Messages returns up to the last n formatted events that have text.
+func (log *EventLog) Messages(n int) []string {
+	return messages(log.All(), n)
+}
+
+func messages(all []Event, n int) []string {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(all) {
+		n = len(all)
+	}
+	var messages = make([]string, 0, n)
+	for i := len(all) - 1; i >= 0 && len(messages) < n; i-- {
+		if text := FormatEvent(all[i]); text != "" {
+			messages = append(messages, text)
+		}
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+func (level *Level) header(pos Position) EventHeader {
+	return EventHeader{level.turn, pos}
+}
+
+func (level *Level) publish(e Event) {
+	level.Events.Publish(e)
+}
+
+// fight resolves a melee exchange between two characters and publishes
+// the blows, including the counter-attack. The scheduler charges the
+// attacker for it.
+func (level *Level) fight(attacker, defender *Character) {
+	var result = level.resolver().Fight(attacker, defender)
+	level.publishStrike(attacker, defender, result.Strike)
+	if result.Counter != nil {
+		level.publishStrike(defender, attacker, *result.Counter)
+	}
+}
+
+// shoot is a single blow from a distance, the defender can't answer.
+func (level *Level) shoot(attacker, defender *Character) {
+	level.publishStrike(attacker, defender, level.resolver().Strike(attacker, defender))
+}
+
+func (level *Level) publishStrike(attacker, defender *Character, s Strike) {
+	var (
+		actor  = participant(attacker, level)
+		target = participant(defender, level)
+		header = level.header(defender.Position)
+	)
+	level.publish(AttackEvent{header, actor, target, attacker.Position, attacker.GetAttackPower(), s.Hit, s.Critical, s.HitChance, s.Roll})
+	if s.Hit {
+		level.publish(DamageEvent{header, actor, target, s.Damage, s.Blocked, defender.Hitpoints})
+	}
+}
+
+// PlayerDeath finds the event in which the player died, if they did.
+func (level *Level) PlayerDeath() (DeathEvent, bool) {
+	return playerDeath(level.Events.All())
+}
+
+func playerDeath(events []Event) (DeathEvent, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if e, ok := events[i].(DeathEvent); ok && e.Target.Player {
+			return e, true
+		}
+	}
+	return DeathEvent{}, false
+}
+
+// checkDeath publishes a DeathEvent if victim has no hitpoints left.
+func (level *Level) checkDeath(killer, victim *Character) bool {
+	if victim.Hitpoints > 0 {
+		return false
+	}
+	level.publish(DeathEvent{level.header(victim.Position), participant(killer, level), participant(victim, level)})
+	return true
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func notice(turn int) Event {
+	return NoticeEvent{EventHeader: EventHeader{Turn: turn}, Text: fmt.Sprint(turn)}
+}
+
+// turns are the turns of events, to compare them by.
+func turns(events []Event) []int {
+	var result = make([]int, 0, len(events))
+	for _, e := range events {
+		result = append(result, e.Header().Turn)
+	}
+	return result
+}
+
+func TestEventLog(t *testing.T) {
+	var log = NewEventLog(3)
+	for turn := 1; turn <= 5; turn++ {
+		log.Publish(notice(turn))
+	}
+	if log.Len() != 3 || log.Cap() != 3 {
+		t.Errorf("len %d cap %d, want 3 and 3", log.Len(), log.Cap())
+	}
+	var tests = []struct {
+		name string
+		got  []Event
+		want []int
+	}{
+		{"all", log.All(), []int{3, 4, 5}},
+		{"recent 2", log.Recent(2), []int{4, 5}},
+		{"recent 10", log.Recent(10), []int{3, 4, 5}},
+		{"recent 0", log.Recent(0), []int{}},
+		{"recent -1", log.Recent(-1), []int{}},
+		{"since 4", log.Since(4), []int{4, 5}},
+		{"since 1", log.Since(1), []int{3, 4, 5}},
+		{"since 6", log.Since(6), []int{}},
+	}
+	for _, test := range tests {
+		if got := turns(test.got); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: turns %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if log := NewEventLog(0); log.Cap() != 1 {
+		t.Errorf("capacity 0 keeps %d", log.Cap())
+	}
+}
+
+func TestMessages(t *testing.T) {
+	var log = NewEventLog(10)
+	log.Publish(notice(1))
+	// moves aren't shown
+	log.Publish(MoveEvent{EventHeader: EventHeader{Turn: 2}})
+	log.Publish(notice(3))
+	log.Publish(notice(4))
+	var tests = []struct {
+		n    int
+		want []string
+	}{
+		{2, []string{"3", "4"}},
+		{3, []string{"1", "3", "4"}},
+		{100, []string{"1", "3", "4"}},
+		{0, []string{}},
+		{-1, []string{}},
+	}
+	for _, test := range tests {
+		if got := log.Messages(test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Messages(%d) = %q, want %q", test.n, got, test.want)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	var (
+		log   = NewEventLog(10)
+		calls []string
+		unsub []func()
+	)
+	for _, name := range []string{"a", "b", "c"} {
+		var name = name
+		unsub = append(unsub, log.Subscribe(func(e Event) {
+			calls = append(calls, fmt.Sprint(name, e.Header().Turn))
+			if name == "a" && e.Header().Turn == 2 {
+				// unsubscribing itself mid-Publish mustn't skip b or call c twice
+				unsub[0]()
+			}
+		}))
+	}
+	log.Publish(notice(1))
+	log.Publish(notice(2))
+	log.Publish(notice(3))
+	unsub[2]()
+	log.Publish(notice(4))
+	unsub[2]()
+	var want = []string{"a1", "b1", "c1", "a2", "b2", "c2", "b3", "c3", "b4"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,99 @@
+package game
+
+// SightRadius is how far the player sees in a lit dungeon.
+const SightRadius = 8
+
+func blocksSight(level *Level, pos Position) bool {
+	if !inRange(level, pos) {
+		return true
+	}
+	switch level.Map[pos.Y][pos.X] {
+	case StoneWall, CloseDoor, Blank:
+		return true
+	default:
+		return false
+	}
+}
+
+// quadrants turn a scan's (depth, column) into an offset from the
+// origin: up, right, down and left.
+var quadrants = [4][4]int{
+	{0, 1, -1, 0},
+	{1, 0, 0, 1},
+	{0, 1, 1, 0},
+	{-1, 0, 0, 1},
+}
+
+// slope is num/den with den > 0, kept as a fraction so that the tests at
+// tile edges and centres are exact.
+type slope struct{ num, den int }
+
+// floorDiv rounds a/b down for b > 0.
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+// FOV returns the tiles visible from origin within radius using symmetric
+// shadowcasting: a floor tile is seen when its centre is in the light, so
+// one floor tile sees another exactly when it is seen back. Walls and
+// closed doors are visible but block what's behind them.
+func (level *Level) FOV(origin Position, radius int) map[Position]bool {
+	var visible = map[Position]bool{origin: true}
+	for _, q := range quadrants {
+		level.scan(visible, origin, radius, q, 1, slope{-1, 1}, slope{1, 1})
+	}
+	return visible
+}
+
+// scan lights the row depth away from origin between the start and end
+// slopes, then the rows behind it that the light reaches.
+func (level *Level) scan(visible map[Position]bool, origin Position, radius int, q [4]int, depth int, start, end slope) {
+	if depth > radius {
+		return
+	}
+	var (
+		// the columns whose tiles the light touches, rounding ties outwards
+		first     = floorDiv(2*depth*start.num+start.den, 2*start.den)
+		last      = -floorDiv(-(2*depth*end.num - end.den), 2*end.den)
+		prevWall  = false
+		seenTiles = false
+	)
+	for col := first; col <= last; col++ {
+		var (
+			pos  = Position{origin.X + depth*q[0] + col*q[1], origin.Y + depth*q[2] + col*q[3]}
+			wall = blocksSight(level, pos)
+			// the tile's centre is in the light
+			lit = col*start.den >= depth*start.num && col*end.den <= depth*end.num
+		)
+		if (wall || lit) && col*col+depth*depth <= radius*radius && inRange(level, pos) {
+			visible[pos] = true
+		}
+		if seenTiles && prevWall && !wall {
+			start = slope{2*col - 1, 2 * depth}
+		}
+		if seenTiles && !prevWall && wall {
+			level.scan(visible, origin, radius, q, depth+1, start, slope{2*col - 1, 2 * depth})
+		}
+		prevWall, seenTiles = wall, true
+	}
+	if seenTiles && !prevWall {
+		level.scan(visible, origin, radius, q, depth+1, start, end)
+	}
+}
+
+// updateVision recomputes what the party sees together and adds it to
+// the level's explored memory.
+func (level *Level) updateVision() {
+	level.Visible = level.FOV(level.Player.Position, SightRadius)
+	for _, p := range level.Players[1:] {
+		for pos := range level.FOV(p.Position, SightRadius) {
+			level.Visible[pos] = true
+		}
+	}
+	for pos := range level.Visible {
+		level.Explored[pos] = true
+	}
+}
@@ -0,0 +1,89 @@
+package game
+
+import "testing"
+
+// fovRows are a room with a pillar and two doors, closed and open, to a
+// corridor.
+var fovRows = []string{
+	"###########",
+	"#@........#",
+	"#...#.....#",
+	"#.........#",
+	"####|###/##",
+	"#.........#",
+	"###########",
+}
+
+func TestFOV(t *testing.T) {
+	var (
+		pillar = []string{"#######", "#..@..#", "#..#..#", "#.....#", "#######"}
+		closed = []string{"#####", "#.@.#", "##|##", "#...#", "#####"}
+		open   = []string{"#####", "#.@.#", "##/##", "#...#", "#####"}
+	)
+	var tests = []struct {
+		name    string
+		rows    []string
+		pos     Position
+		visible bool
+	}{
+		{"origin", pillar, Position{3, 1}, true},
+		{"floor", pillar, Position{1, 3}, true},
+		{"wall", pillar, Position{3, 2}, true},
+		{"corner", pillar, Position{0, 0}, true},
+		{"behind a wall", pillar, Position{3, 3}, false},
+		{"closed door", closed, Position{2, 2}, true},
+		{"behind a closed door", closed, Position{2, 3}, false},
+		{"behind an open door", open, Position{2, 3}, true},
+	}
+	for _, test := range tests {
+		var level = testWorld(t, test.rows...).Level()
+		if visible := level.FOV(level.Player.Position, SightRadius)[test.pos]; visible != test.visible {
+			t.Errorf("%s: %v visible %v, want %v", test.name, test.pos, visible, test.visible)
+		}
+	}
+}
+
+func TestFOVRadius(t *testing.T) {
+	var level = testWorld(t, "###############", "#@............#", "#.............#", "###############").Level()
+	var visible = level.FOV(Position{1, 1}, 5)
+	for x := 1; x < 14; x++ {
+		if want := x-1 <= 5; visible[Position{x, 1}] != want {
+			t.Errorf("%d tiles away: visible %v, want %v", x-1, visible[Position{x, 1}], want)
+		}
+	}
+	// the radius is round, not square
+	if visible[Position{6, 2}] {
+		t.Error("sees past the radius diagonally")
+	}
+	for pos := range level.FOV(Position{1, 1}, 0) {
+		if pos != (Position{1, 1}) {
+			t.Errorf("radius 0 sees %v", pos)
+		}
+	}
+}
+
+// TestFOVSymmetric checks that every floor tile that sees another is seen
+// back from it, so that a monster sees the player just when the player
+// sees the monster.
+func TestFOVSymmetric(t *testing.T) {
+	var level = testWorld(t, fovRows...).Level()
+	var floor []Position
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile == DirtFloor {
+				floor = append(floor, Position{x, y})
+			}
+		}
+	}
+	var views = make(map[Position]map[Position]bool)
+	for _, pos := range floor {
+		views[pos] = level.FOV(pos, SightRadius)
+	}
+	for _, a := range floor {
+		for _, b := range floor {
+			if views[a][b] != views[b][a] {
+				t.Errorf("%v sees %v: %v, but %v sees %v: %v", a, b, views[a][b], b, a, views[b][a])
+			}
+		}
+	}
+}
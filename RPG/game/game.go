@@ -1,25 +1,108 @@
 package game
 
 import (
-	"bufio"
-	"fmt"
-	"math"
+	"embed"
+	"experiments/experiments/RPG/pathfind"
+	"io"
 	"os"
+	"time"
 )
 
 type Game struct {
-	LevelChans []chan *Level
+	LevelChans []chan *Snapshot
 	InputChan  chan *Input
-	Level      *Level
+	World      *World
+	Simulator  *Simulator
+	// Bestiary is what the maps spawn, on Restart too
+	Bestiary Bestiary
+
+	// paths are reloaded on Restart
+	paths []string
+	// seats are the views that joined, in order, and whom they play
+	seats []seat
+	// last is the latest snapshot sent, ids the monsters' and players'
+	// in it
+	last     *Snapshot
+	ids      map[*Character]int
+	nextID   int
+	recorder *recorder // see Record
+	done     chan bool
 }
 
-func NewGame(numWindows int, path string) *Game {
-	levelChans := make([]chan *Level, numWindows)
+// NewGame loads one level per path, top level first, with the monsters of
+// bestiary.
+func NewGame(numWindows int, bestiary Bestiary, paths ...string) (*Game, error) {
+	world, err := loadWorld(bestiary, paths)
+	if err != nil {
+		return nil, err
+	}
+	levelChans := make([]chan *Snapshot, numWindows)
 	for i := range levelChans {
-		levelChans[i] = make(chan *Level)
+		levelChans[i] = NewLevelChan()
 	}
 	inputChan := make(chan *Input)
-	return &Game{levelChans, inputChan, loadLevelFromFile(path)}
+	return &Game{
+		LevelChans: levelChans,
+		InputChan:  inputChan,
+		World:      world,
+		Simulator:  NewSimulator(world),
+		Bestiary:   bestiary,
+		paths:      paths,
+		done:       make(chan bool),
+	}, nil
+}
+
+// Done is closed once Run, or Replay.Play in its place, returns. Nothing
+// reads InputChan after that.
+func (game *Game) Done() <-chan bool {
+	return game.done
+}
+
+func loadWorld(bestiary Bestiary, paths []string) (*World, error) {
+	var levels = make([]*Level, len(paths))
+	for i, path := range paths {
+		level, err := loadLevelFromFile(path, bestiary)
+		if err != nil {
+			return nil, err
+		}
+		levels[i] = level
+	}
+	return NewWorld(levels...)
+}
+
+// Restart reloads the levels from their files and starts over with a
+// fresh party as big as the old one. The combat rules and generator carry
+// on from the old game so that the new one doesn't replay the same dice,
+// and so do diagonal moves.
+func (game *Game) Restart() error {
+	world, err := loadWorld(game.Bestiary, game.paths)
+	if err != nil {
+		return err
+	}
+	if game.World != nil {
+		if game.World.Combat != nil {
+			world.SetCombat(game.World.Combat)
+		}
+		world.SetDiagonal(game.World.Diagonal)
+		for len(world.Players) < len(game.World.Players) {
+			world.AddPlayer()
+		}
+	}
+	game.World = world
+	game.Simulator = NewSimulator(world)
+	game.reseat()
+	return nil
+}
+
+// GameOver is true once the player has died, the whole party if there's
+// more than one, until Restart.
+func (game *Game) GameOver() bool {
+	return game.Simulator.GameOver
+}
+
+// Level is the level the player is on.
+func (game *Game) Level() *Level {
+	return game.World.Level()
 }
 
 type InputType int
@@ -32,12 +115,37 @@ const (
 	Right
 	QuitGame
 	CloseWindow
-	Search //temporary
+	// Explore walks towards the nearest unexplored tile until something
+	// happens, Travel to Input.Target
+	Explore
+	Pickup
+	Drop
+	Use
+	// Restart starts a new game, usually from the death screen
+	Restart
+	Travel
+	UpLeft
+	UpRight
+	DownLeft
+	DownRight
+	// OpenWindow attaches LevelChannel as another view, CloseWindow
+	// detaches it
+	OpenWindow
+	// Join attaches LevelChannel as a view with a player of its own, see
+	// World.AddPlayer. CloseWindow takes the player out of the game.
+	Join
 )
 
 type Input struct {
-	Type         InputType
-	LevelChannel chan *Level
+	Type InputType
+	// LevelChannel is the view for OpenWindow, Join and CloseWindow, see
+	// NewLevelChan. The other inputs are played by its player if it
+	// joined, by World.Player if not; Restart needs a view that joined.
+	LevelChannel chan *Snapshot
+	// Item is the inventory index for Drop and Use
+	Item int
+	// Target is where to Travel to
+	Target Position
 }
 type Title rune
 
@@ -46,21 +154,41 @@ const (
 	DirtFloor Title = '.'
 	CloseDoor Title = '|'
 	OpenDoor  Title = '/'
+	UpStair   Title = '<'
+	DownStair Title = '>'
+	Mud       Title = ','
+	Water     Title = '~'
 	Blank     Title = 0
 	Pending   Title = -1
 )
 
 type Level struct {
-	Map           [][]Title
-	Player        *Player
-	Monsters      map[Position]*Monster
-	Debug         map[Position]bool
-	Events        []string
-	EventPosition int
+	Name     string
+	Map      [][]Title
+	Player   *Player
+	Players  []*Player // the party, Player first
+	Monsters map[Position]*Monster
+	Triggers map[Position]string
+	Debug    map[Position]bool
+	Events   *EventLog
+	Items    map[Position][]*Item
+	Visible  map[Position]bool
+	Explored map[Position]bool
+
+	start    Position
+	hasStart bool
+	turn     int
+	combat   *Resolver
+	diagonal bool // see World.SetDiagonal
 }
 
 type Player struct {
 	Character
+	Inventory []*Item
+	// Capacity is how many items fit in the inventory, 0 means InventoryCapacity
+	Capacity   int
+	Level      int
+	Experience int
 }
 
 type Attackable interface {
@@ -68,7 +196,13 @@ type Attackable interface {
 	SetActionPints(float64)
 	GetHitpoints() int
 	SetHitpoints(int)
+	GetMaxHitpoints() int
 	GetAttackPower() int
+	GetDefense() int
+	GetAccuracy() int
+	GetEvasion() int
+	GetCritChance() int
+	GetDamageRange() (int, int)
 }
 
 func (c *Character) GetActionPints() float64 {
@@ -87,26 +221,46 @@ func (c *Character) SetHitpoints(hp int) {
 	c.Hitpoints = hp
 }
 
+func (c *Character) GetMaxHitpoints() int {
+	return c.MaxHitpoints
+}
+
 func (c *Character) GetAttackPower() int {
+	if c.Weapon != nil {
+		return c.Strength + c.Weapon.Attack
+	}
 	return c.Strength
 }
 
-func Attack(a1, a2 Attackable) {
-	a1.SetActionPints(a1.GetActionPints() - 1)
-	a2.SetHitpoints(a2.GetHitpoints() - a1.GetAttackPower())
-	if a2.GetHitpoints() > 0 {
-		a2.SetActionPints(a2.GetActionPints() - 1)
-		a1.SetHitpoints(a1.GetHitpoints() - a2.GetAttackPower())
+func (c *Character) GetDefense() int {
+	if c.Armor != nil {
+		return c.Armor.Defense
 	}
-	fmt.Println("PlayerAttackMonster")
+	return 0
+}
+
+func (c *Character) GetAccuracy() int {
+	return c.Accuracy
+}
+
+func (c *Character) GetEvasion() int {
+	return c.Evasion
+}
+
+func (c *Character) GetCritChance() int {
+	return c.CritChance
 }
 
-func (level *Level) AddEvent(event string) {
-	level.Events[level.EventPosition] = event
-	level.EventPosition++
-	if level.EventPosition == len(level.Events) {
-		level.EventPosition = 0
+// GetDamageRange is Strength plus whatever the weapon rolls.
+func (c *Character) GetDamageRange() (int, int) {
+	if c.Weapon == nil {
+		return c.Strength, c.Strength
+	}
+	var lo, hi = c.Strength + c.Weapon.Attack, c.Strength + c.Weapon.MaxAttack
+	if hi < lo {
+		hi = lo
 	}
+	return lo, hi
 }
 
 type Position struct {
@@ -122,93 +276,97 @@ type Entity struct {
 type Character struct {
 	Entity
 	Hitpoints    int
+	MaxHitpoints int
 	Strength     int
 	Speed        float64
 	ActionPoints float64
+	// Accuracy and Evasion move the hit chance up and down, CritChance
+	// adds to the rules' critical chance. All in percent.
+	Accuracy   int
+	Evasion    int
+	CritChance int
+	Weapon     *Item
+	Armor      *Item
 }
 
-func loadLevelFromFile(fileName string) *Level {
-	file, err := os.Open(fileName)
-	if err != nil {
-		panic(err)
+const eventHistory = 256
+
+// NewLevel returns an empty (all Blank) level of the given size
+// with a fresh player at 0,0 and no monsters.
+func NewLevel(name string, width, height int) *Level {
+	var player = newPlayer()
+	var level = &Level{
+		Name:     name,
+		Player:   player,
+		Players:  []*Player{player},
+		Monsters: make(map[Position]*Monster),
+		Triggers: make(map[Position]string),
+		Events:   NewEventLog(eventHistory),
+		Items:    make(map[Position][]*Item),
+		Visible:  make(map[Position]bool),
+		Explored: make(map[Position]bool),
+		Map:      make([][]Title, height),
 	}
-	defer file.Close()
-	var (
-		scanner    = bufio.NewScanner(file)
-		levelLines = make([]string, 0)
-		longestRaw = 0
-		index      = 0
-	)
-	for scanner.Scan() {
-		levelLines = append(levelLines, scanner.Text())
-		if count := len(levelLines[index]); count > longestRaw {
-			longestRaw = count
-		}
-		index++
-	}
-
-	level := &Level{
-		Player: &Player{
-			Character{
-				Entity: Entity{
-					Name: "GoMen",
-					Rune: '@',
-				},
-				Hitpoints:    20,
-				Strength:     20,
-				Speed:        1.0,
-				ActionPoints: 0,
-			}},
-		Events: make([]string, 10),
-	}
-
-	level.Map = make([][]Title, len(levelLines))
-	level.Monsters = make(map[Position]*Monster)
 	for i := range level.Map {
-		level.Map[i] = make([]Title, longestRaw)
-	}
-	var lenMap = len(level.Map)
-	for y := 0; y < lenMap; y++ {
-		var line = levelLines[y]
-		for x, c := range line {
-			var t Title
-			switch c {
-			case ' ', '\t', '\n', '\r':
-				t = Blank
-			case '#':
-				t = StoneWall
-			case '|':
-				t = CloseDoor
-			case '/':
-				t = OpenDoor
-			case '.':
-				t = DirtFloor
-			case '@':
-				level.Player.X = x
-				level.Player.Y = y
-				t = Pending
-			case 'R':
-				level.Monsters[Position{x, y}] = NewRat(Position{x, y})
-				t = Pending
-			case 'S':
-				level.Monsters[Position{x, y}] = NewSpider(Position{x, y})
-				t = Pending
-			default:
-				panic(fmt.Sprintf(`Invalid character '%d' in map`, c))
-			}
-			level.Map[y][x] = t
-		}
+		level.Map[i] = make([]Title, width)
 	}
-	for y, row := range level.Map {
-		for x, tile := range row {
-			if tile == Pending {
-				level.Map[y][x] = level.bfsFloor(Position{x, y})
-			}
-		}
+	return level
+}
+
+// SetStart puts the player at pos and makes it the level's start.
+func (level *Level) SetStart(pos Position) {
+	level.Player.Position = pos
+	level.start = pos
+	level.hasStart = true
+}
+
+func newPlayer() *Player {
+	return &Player{
+		Character: Character{
+			Entity: Entity{
+				Name: "GoMen",
+				Rune: '@',
+			},
+			Hitpoints:    20,
+			MaxHitpoints: 20,
+			Strength:     20,
+			Speed:        1.0,
+			ActionPoints: 0,
+		},
+		Level: 1,
 	}
+}
 
-	return level
+// OpenAsset opens maps and bestiaries by name. Names are plain paths
+// unless main hands it an asset loader.
+var OpenAsset = func(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+// Files are the stock maps and bestiary, compiled in for an asset loader
+// to fall back on. Their names are relative to this package's directory.
+//
+//go:embed maps/*.map maps/bestiary.txt
+var Files embed.FS
 
+func loadLevelFromFile(fileName string, bestiary Bestiary) (*Level, error) {
+	file, err := OpenAsset(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mf, err := ParseMap(file, bestiary)
+	if err == nil {
+		var level *Level
+		if level, err = mf.Level(); err == nil {
+			return level, nil
+		}
+	}
+	if mapErr, ok := err.(*MapError); ok {
+		mapErr.File = fileName
+	}
+	return nil, err
 }
 
 func inRange(level *Level, pos Position) bool {
@@ -227,204 +385,243 @@ func canWalk(level *Level, pos Position) bool {
 	return false
 }
 
-func checkDoor(level *Level, pos Position) {
-	if level.Map[pos.Y][pos.X] == CloseDoor {
+// openDoor opens a closed door at pos, reports whether there was one.
+func openDoor(level *Level, pos Position, opener *Character) bool {
+	if inRange(level, pos) && level.Map[pos.Y][pos.X] == CloseDoor {
 		level.Map[pos.Y][pos.X] = OpenDoor
+		level.publish(DoorOpenedEvent{level.header(pos), participant(opener, level)})
+		return true
 	}
+	return false
 }
 
 func (p *Player) Move(pos Position, level *Level) {
-	if monsters, ok := level.Monsters[pos]; !ok {
-		p.Position = pos
-	} else {
-		Attack(level.Player, monsters)
-		level.AddEvent("Player Attacked Monster")
-		if monsters.Hitpoints <= 0 {
-			delete(level.Monsters, monsters.Position)
-		}
-		if level.Player.Hitpoints <= 0 {
-			fmt.Println("YOU DIED")
-			panic("YOU DIED")
+	if monster, ok := level.Monsters[pos]; ok {
+		level.fight(&p.Character, &monster.Character)
+		if level.checkDeath(&p.Character, &monster.Character) {
+			level.killMonster(monster, p)
 		}
+		level.checkDeath(&monster.Character, &p.Character)
+	} else if level.playerAt(pos) == nil {
+		var from = p.Position
+		p.Position = pos
+		level.publish(MoveEvent{level.header(pos), participant(&p.Character, level), from})
 	}
 }
 
-func (game *Game) handleInput(input *Input) {
-	var (
-		level  = game.Level
-		player = level.Player
-	)
-	switch input.Type {
-	case Up:
-		newPos := Position{player.X, player.Y - 1}
-		if canWalk(game.Level, newPos) {
-			level.Player.Move(newPos, level)
-		} else {
-			checkDoor(game.Level, newPos)
-		}
-	case Down:
-		newPos := Position{player.X, player.Y + 1}
-		if canWalk(game.Level, newPos) {
-			player.Move(newPos, level)
-		} else {
-			checkDoor(game.Level, newPos)
-		}
-	case Left:
-		newPos := Position{player.X - 1, player.Y}
-		if canWalk(game.Level, newPos) {
-			player.Move(newPos, level)
-		} else {
-			checkDoor(game.Level, newPos)
-		}
-	case Right:
-		newPos := Position{player.X + 1, player.Y}
-		if canWalk(game.Level, newPos) {
-			player.Move(newPos, level)
-		} else {
-			checkDoor(game.Level, newPos)
-		}
-	case Search:
-		//bfs(ui, level, player.Position)
-		level.astar(player.Position, Position{3, 2})
-	case CloseWindow:
-		close(input.LevelChannel)
-		chanIndex := 0
-		for i, c := range game.LevelChans {
-			if input.LevelChannel == c {
-				chanIndex = i
-				break
-			}
-		}
-		game.LevelChans = append(game.LevelChans[:chanIndex], game.LevelChans[chanIndex+1:]...)
+func getNeighbors(level *Level, pos Position) []Position {
+	return neighborsWhere(level, pos, canWalk)
+}
 
-	}
+// canPass is canWalk that also lets closed doors through,
+// they only cost a turn to open.
+func canPass(level *Level, pos Position) bool {
+	return canWalk(level, pos) || inRange(level, pos) && level.Map[pos.Y][pos.X] == CloseDoor
 }
 
-func getNeighbors(level *Level, pos Position) []Position {
-	var (
-		neighbors = make([]Position, 0, 4)
-		left      = Position{pos.X - 1, pos.Y}
-		right     = Position{pos.X + 1, pos.Y}
-		up        = Position{pos.X, pos.Y - 1}
-		down      = Position{pos.X, pos.Y + 1}
-	)
-	if canWalk(level, right) {
-		neighbors = append(neighbors, right)
-	}
-	if canWalk(level, left) {
-		neighbors = append(neighbors, left)
-	}
-	if canWalk(level, up) {
-		neighbors = append(neighbors, up)
+// steps lead to the tiles around a position: right, left, up and down,
+// then the diagonals.
+var steps = []Position{{1, 0}, {-1, 0}, {0, -1}, {0, 1}, {-1, -1}, {1, -1}, {-1, 1}, {1, 1}}
+
+// steps are those the level allows.
+func (level *Level) steps() []Position {
+	if level.diagonal {
+		return steps
 	}
-	if canWalk(level, down) {
-		neighbors = append(neighbors, down)
+	return steps[:4]
+}
+
+// neighborsWhere are the tiles a step from pos that walkable allows. A
+// diagonal one also needs both tiles on the corner it goes around.
+func neighborsWhere(level *Level, pos Position, walkable func(*Level, Position) bool) []Position {
+	var neighbors = make([]Position, 0, 8)
+	for _, d := range level.steps() {
+		var next = Position{pos.X + d.X, pos.Y + d.Y}
+		if !walkable(level, next) {
+			continue
+		}
+		if d.X != 0 && d.Y != 0 && (!walkable(level, Position{next.X, pos.Y}) || !walkable(level, Position{pos.X, next.Y})) {
+			continue
+		}
+		neighbors = append(neighbors, next)
 	}
 	return neighbors
 }
 
-func (level *Level) bfsFloor(start Position) Title {
-	var frontier = make([]Position, 0, 8)
-	frontier = append(frontier, start)
-	var visited = make(map[Position]bool)
-	visited[start] = true
-	level.Debug = visited
-
-	for len(frontier) > 0 {
-		var current = frontier[0]
-
-		var currentTile = level.Map[current.Y][current.X]
-		switch currentTile {
-		case DirtFloor:
-			return DirtFloor
-		default:
-		}
+// Distances is a BFS from start over every tile the player could reach,
+// counting closed doors as passable. Unreachable tiles are missing.
+func (level *Level) Distances(start Position) map[Position]int {
+	var dist = make(map[Position]int)
+	for p, d := range pathfind.BFS(level.grid(canPass), pathfind.Point(start)) {
+		dist[Position(p)] = d
+	}
+	return dist
+}
 
-		frontier = frontier[1:]
-		for _, next := range getNeighbors(level, current) {
-			if !visited[next] {
-				frontier = append(frontier, next)
-				visited[next] = true
-			}
+// bfsFloor guesses the tile under an entity from the nearest floor,
+// mud or water.
+func (level *Level) bfsFloor(start Position) Title {
+	var isFloor = func(p pathfind.Point) bool {
+		switch level.Map[p.Y][p.X] {
+		case DirtFloor, Mud, Water:
+			return true
 		}
+		return false
+	}
+	if p, ok := pathfind.Nearest(level.grid(canWalk), pathfind.Point(start), isFloor); ok {
+		return level.Map[p.Y][p.X]
 	}
 	return DirtFloor
 }
 
+// astar is the cheapest path from start to goal through doors and over
+// the terrain costs, both ends included. Nil if there is none.
 func (level *Level) astar(start, goal Position) []Position {
-	var frontier = make(pQueue, 0, 8)
-	frontier = frontier.push(start, 1)
-	var cameFrom = make(map[Position]Position)
-	cameFrom[start] = start
-	var costSoFor = make(map[Position]int)
-	costSoFor[start] = 0
-
-	//level.Debug = make(map[Position]bool)
-	var current Position
-	for len(frontier) > 0 {
-
-		frontier, current = frontier.pop()
-
-		if current == goal {
-			var path = make([]Position, 0)
-			var pos = current
-			for pos != start {
-				path = append(path, pos)
-				pos = cameFrom[pos]
-			}
-			path = append(path, pos)
+	var (
+		grid    = level.grid(canPass)
+		path, _ = pathfind.AStar(grid, pathfind.Point(start), pathfind.Point(goal), grid.Heuristic(minTerrainCost()))
+	)
+	if path == nil {
+		return nil
+	}
+	var positions = make([]Position, len(path))
+	for i, p := range path {
+		positions[i] = Position(p)
+	}
+	return positions
+}
 
-			// Инверсия [3,2,1] = [1,2,3]
-			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
-				path[i], path[j] = path[j], path[i]
-			}
+// NewLevelChan makes a channel for a view to attach with OpenWindow. It
+// holds one snapshot so that the game never waits for a view to catch up.
+func NewLevelChan() chan *Snapshot {
+	return make(chan *Snapshot, 1)
+}
 
-			//for _, pos := range path {
-			//	level.Debug[pos] = true
-			//}
-			return path
+func (game *Game) openWindow(levelChan chan *Snapshot) {
+	for _, c := range game.LevelChans {
+		if c == levelChan {
+			return
 		}
-		for _, next := range getNeighbors(level, current) {
-			var newCost = costSoFor[current] + 1 // always 1 for now
-			if _, ok := costSoFor[next]; !ok || newCost < costSoFor[next] {
-				costSoFor[next] = newCost
-				var (
-					xDist    = int(math.Abs(float64(goal.X - next.X)))
-					yDist    = int(math.Abs(float64(goal.Y - next.Y)))
-					priority = newCost + xDist + yDist
-				)
-				frontier = frontier.push(next, priority)
-				//level.Debug[next] = true
-				cameFrom[next] = current
+	}
+	game.LevelChans = append(game.LevelChans, levelChan)
+	if game.last == nil {
+		game.snapshot()
+	}
+	send(levelChan, game.viewFor(game.last, game.playerFor(levelChan)))
+}
 
-			}
+func (game *Game) closeWindow(levelChan chan *Snapshot) {
+	game.leave(levelChan)
+	for i, c := range game.LevelChans {
+		if levelChan == c {
+			close(c)
+			game.LevelChans = append(game.LevelChans[:i], game.LevelChans[i+1:]...)
+			return
 		}
 	}
-	return nil
 }
 
-func (game *Game) Run() {
-	fmt.Println("Starting...")
-
+// broadcast hands every view a snapshot of the current level without
+// waiting on any of them. A view that hasn't taken the last one gets this
+// one instead. They all share it but for the player, snapshots are
+// read-only.
+func (game *Game) broadcast() {
+	var snapshot = game.snapshot()
 	for _, lChan := range game.LevelChans {
-		lChan <- game.Level
+		send(lChan, game.viewFor(snapshot, game.playerFor(lChan)))
 	}
-	for input := range game.InputChan {
-		if input.Type == QuitGame {
-			return
-		}
+}
+
+// send is only ever called from Run, so once the stale snapshot is out
+// there is room for the new one. Unbuffered channels just miss some.
+func send(levelChan chan *Snapshot, snapshot *Snapshot) {
+	select {
+	case levelChan <- snapshot:
+		return
+	default:
+	}
+	select {
+	case <-levelChan:
+	default:
+	}
+	select {
+	case levelChan <- snapshot:
+	default:
+	}
+}
 
-		for _, monster := range game.Level.Monsters {
-			monster.Update(game.Level)
+// travelDelay is the pause between the steps of a travel so that it can
+// be watched and interrupted.
+const travelDelay = 60 * time.Millisecond
+
+// Run is the channel adapter over Simulator: it reads InputChan, steps
+// one turn per input and sends a snapshot of the level to every
+// LevelChan. Views come and go with OpenWindow, Join and CloseWindow; the
+// game ends with the last one. Inputs that can't be played are dropped,
+// the simulator tells the player why where it matters. Everything Run
+// does to the game goes into the replay if one is being recorded; it
+// returns the error if that failed or a Restart did.
+func (game *Game) Run() (err error) {
+	defer close(game.done)
+	defer func() { err = game.finishRecording() }()
+
+	game.broadcast()
+	for {
+		var input *Input
+		if game.Simulator.Traveling() {
+			// keep walking until the player does something else
+			select {
+			case input = <-game.InputChan:
+			case <-time.After(travelDelay):
+				game.record(replayEntry{Continue: true})
+				game.Simulator.Continue()
+				game.broadcast()
+				continue
+			}
+		} else {
+			var ok bool
+			if input, ok = <-game.InputChan; !ok {
+				return
+			}
 		}
 
-		if input.Type == CloseWindow {
-			// TODO 	windows.Close() fd Handle in ui
+		switch input.Type {
+		case QuitGame:
 			return
+		case OpenWindow:
+			game.openWindow(input.LevelChannel)
+			continue
+		case Join:
+			game.join(input.LevelChannel)
+			game.broadcast()
+			continue
+		case CloseWindow:
+			var left = game.seatOf(input.LevelChannel) != nil
+			game.closeWindow(input.LevelChannel)
+			if len(game.LevelChans) == 0 {
+				return
+			}
+			if left {
+				game.broadcast()
+			}
+			continue
+		case Restart:
+			// only a player who died may start over, and only once the
+			// others have too
+			if seat := game.seatOf(input.LevelChannel); seat == nil || seat.player.Hitpoints > 0 || !game.GameOver() {
+				continue
+			}
+			game.recordInput(input.LevelChannel, *input)
+			if err := game.Restart(); err != nil {
+				return err
+			}
+			game.broadcast()
+			continue
 		}
-		game.handleInput(input)
-		for _, lChan := range game.LevelChans {
-			lChan <- game.Level
-		}
+
+		game.recordInput(input.LevelChannel, *input)
+		// errors leave the game as it was, or with a notice to show
+		game.Simulator.StepFor(game.playerFor(input.LevelChannel), *input)
+		game.broadcast()
 	}
 }
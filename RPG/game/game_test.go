@@ -0,0 +1,99 @@
+package game
+
+import "testing"
+
+func stockBestiary(t *testing.T) Bestiary {
+	t.Helper()
+	bestiary, err := StockBestiary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bestiary
+}
+
+func newTestGame(t *testing.T) *Game {
+	t.Helper()
+	game, err := NewGame(1, stockBestiary(t), "maps/entrance.map", "maps/level_2.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+// runGame plays inputs through Run and returns once it did.
+func runGame(t *testing.T, game *Game, inputs ...*Input) {
+	t.Helper()
+	var done = make(chan error)
+	go func() { done <- game.Run() }()
+	for _, input := range inputs {
+		game.InputChan <- input
+	}
+	game.InputChan <- &Input{Type: QuitGame}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	var tests = []struct {
+		name    string
+		dead    bool
+		join    bool
+		restart bool
+	}{
+		{"alive", false, true, false},
+		{"no seat", true, false, false},
+		{"dead", true, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				game  = newTestGame(t)
+				view  = game.LevelChans[0]
+				world = game.World
+			)
+			if test.dead {
+				game.World.Player.Hitpoints = 0
+				game.Simulator.GameOver = true
+			}
+			var inputs []*Input
+			if test.join {
+				inputs = append(inputs, &Input{Type: Join, LevelChannel: view})
+			}
+			runGame(t, game, append(inputs, &Input{Type: Restart, LevelChannel: view})...)
+			if restarted := game.World != world; restarted != test.restart {
+				t.Errorf("restarted = %v, want %v", restarted, test.restart)
+			}
+		})
+	}
+}
+
+// TestDiagonal checks that the helpers agree on whether a level has
+// diagonal steps.
+func TestDiagonal(t *testing.T) {
+	var tests = []struct {
+		diagonal  bool
+		neighbors int
+		distance  int
+		borders   bool
+	}{
+		{false, 3, 4, false},
+		{true, 5, 2, true},
+	}
+	for _, test := range tests {
+		var world = testWorld(t, "#####", "#...#", "#.@.#", "#.#.#", "#####")
+		world.SetDiagonal(test.diagonal)
+		var level = world.Level()
+		// the wall below cuts off the corners on either side of it
+		if n := len(getNeighbors(level, Position{2, 2})); n != test.neighbors {
+			t.Errorf("diagonal %v: %d neighbors, want %d", test.diagonal, n, test.neighbors)
+		}
+		if d := level.distance(Position{1, 1}, Position{3, 3}); d != test.distance {
+			t.Errorf("diagonal %v: distance %d, want %d", test.diagonal, d, test.distance)
+		}
+		level.Explored = map[Position]bool{{2, 2}: true, {1, 2}: true, {3, 2}: true, {2, 1}: true, {2, 3}: true}
+		if b := level.bordersUnexplored(Position{2, 2}); b != test.borders {
+			t.Errorf("diagonal %v: borders unexplored %v, want %v", test.diagonal, b, test.borders)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package gen
+
+import (
+	"experiments/experiments/RPG/game"
+	"math/rand"
+)
+
+type rect struct {
+	x, y, w, h int
+}
+
+func (r rect) center() game.Position {
+	return game.Position{r.x + r.w/2, r.y + r.h/2}
+}
+
+type node struct {
+	area        rect
+	left, right *node
+	room        rect
+}
+
+func (n *node) split(r *rand.Rand, minSize int) {
+	var (
+		canSplitH = n.area.h >= 2*minSize
+		canSplitV = n.area.w >= 2*minSize
+	)
+	if !canSplitH && !canSplitV {
+		return
+	}
+	var horizontal = canSplitH && (!canSplitV || n.area.h > n.area.w || n.area.h == n.area.w && r.Intn(2) == 0)
+	if horizontal {
+		var at = minSize + r.Intn(n.area.h-2*minSize+1)
+		n.left = &node{area: rect{n.area.x, n.area.y, n.area.w, at}}
+		n.right = &node{area: rect{n.area.x, n.area.y + at, n.area.w, n.area.h - at}}
+	} else {
+		var at = minSize + r.Intn(n.area.w-2*minSize+1)
+		n.left = &node{area: rect{n.area.x, n.area.y, at, n.area.h}}
+		n.right = &node{area: rect{n.area.x + at, n.area.y, n.area.w - at, n.area.h}}
+	}
+	n.left.split(r, minSize)
+	n.right.split(r, minSize)
+}
+
+// rooms places a room in every leaf, leaving a tile of wall on each side,
+// and appends the leaves' rooms in tree order.
+func (n *node) rooms(r *rand.Rand, maxSize int, out []rect) []rect {
+	if n.left != nil {
+		out = n.left.rooms(r, maxSize, out)
+		return n.right.rooms(r, maxSize, out)
+	}
+	var (
+		w = randBetween(r, 3, min(maxSize, n.area.w-2))
+		h = randBetween(r, 3, min(maxSize, n.area.h-2))
+		x = n.area.x + 1 + r.Intn(n.area.w-w-1)
+		y = n.area.y + 1 + r.Intn(n.area.h-h-1)
+	)
+	n.room = rect{x, y, w, h}
+	return append(out, n.room)
+}
+
+// anyRoom returns a room under n to hang corridors on.
+func (n *node) anyRoom() rect {
+	if n.left == nil {
+		return n.room
+	}
+	return n.left.anyRoom()
+}
+
+func (n *node) connect(level *game.Level, r *rand.Rand) {
+	if n.left == nil {
+		return
+	}
+	n.left.connect(level, r)
+	n.right.connect(level, r)
+	corridor(level, r, n.left.anyRoom().center(), n.right.anyRoom().center())
+}
+
+func corridor(level *game.Level, r *rand.Rand, from, to game.Position) {
+	var corner = game.Position{to.X, from.Y}
+	if r.Intn(2) == 0 {
+		corner = game.Position{from.X, to.Y}
+	}
+	carveLine(level, from, corner)
+	carveLine(level, corner, to)
+}
+
+func carveLine(level *game.Level, from, to game.Position) {
+	var dx, dy = sign(to.X - from.X), sign(to.Y - from.Y)
+	for pos := from; ; pos = (game.Position{pos.X + dx, pos.Y + dy}) {
+		level.Map[pos.Y][pos.X] = game.DirtFloor
+		if pos == to {
+			return
+		}
+	}
+}
+
+func carveBSP(level *game.Level, r *rand.Rand, p Params) (game.Position, bool) {
+	var minSize = max(p.MinRoomSize, 3) + 2
+	var root = &node{area: rect{0, 0, p.Width, p.Height}}
+	root.split(r, minSize)
+	var rooms = root.rooms(r, max(p.MaxRoomSize, 3), nil)
+
+	for _, room := range rooms {
+		for y := room.y; y < room.y+room.h; y++ {
+			for x := room.x; x < room.x+room.w; x++ {
+				level.Map[y][x] = game.DirtFloor
+			}
+		}
+	}
+	root.connect(level, r)
+	wrapWalls(level)
+	for _, room := range rooms {
+		placeDoors(level, room)
+	}
+	return rooms[0].center(), true
+}
+
+// placeDoors closes every corridor that crosses the ring of tiles around
+// room between two walls.
+func placeDoors(level *game.Level, room rect) {
+	var isWall = func(x, y int) bool {
+		return y < 0 || x < 0 || y >= len(level.Map) || x >= len(level.Map[y]) || level.Map[y][x] == game.StoneWall
+	}
+	for y := room.y - 1; y <= room.y+room.h; y++ {
+		for x := room.x - 1; x <= room.x+room.w; x++ {
+			var onRing = y == room.y-1 || y == room.y+room.h || x == room.x-1 || x == room.x+room.w
+			if !onRing || !isFloor(level.Map[y][x]) {
+				continue
+			}
+			if isWall(x-1, y) && isWall(x+1, y) || isWall(x, y-1) && isWall(x, y+1) {
+				level.Map[y][x] = game.CloseDoor
+			}
+		}
+	}
+}
+
+func randBetween(r *rand.Rand, lo, hi int) int {
+	if hi <= lo {
+		return lo
+	}
+	return lo + r.Intn(hi-lo+1)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"experiments/experiments/RPG/game"
+	"math/rand"
+)
+
+// carveCaves fills the map with random rock, smooths it with the usual
+// 4-5 rule and starts the player in the largest cavern.
+func carveCaves(level *game.Level, r *rand.Rand, p Params) (game.Position, bool) {
+	var (
+		width  = p.Width
+		height = p.Height
+		rock   = make([][]bool, height)
+	)
+	for y := range rock {
+		rock[y] = make([]bool, width)
+		for x := range rock[y] {
+			var border = x == 0 || y == 0 || x == width-1 || y == height-1
+			rock[y][x] = border || r.Intn(100) < p.FillPercent
+		}
+	}
+	for i := 0; i < p.Smoothing; i++ {
+		rock = smooth(rock)
+	}
+
+	for y, row := range rock {
+		for x, isRock := range row {
+			if !isRock {
+				level.Map[y][x] = game.DirtFloor
+			}
+		}
+	}
+	wrapWalls(level)
+
+	// the largest cavern wins, Generate clears the rest
+	var (
+		best     game.Position
+		bestSize = 0
+		seen     = make(map[game.Position]bool)
+	)
+	for y, row := range level.Map {
+		for x, tile := range row {
+			var pos = game.Position{x, y}
+			if !isFloor(tile) || seen[pos] {
+				continue
+			}
+			var cave = level.Distances(pos)
+			for c := range cave {
+				seen[c] = true
+			}
+			if len(cave) > bestSize {
+				best, bestSize = pos, len(cave)
+			}
+		}
+	}
+	if bestSize == 0 {
+		return best, false
+	}
+	var cave = floorTiles(level, level.Distances(best))
+	return cave[r.Intn(len(cave))], true
+}
+
+func smooth(rock [][]bool) [][]bool {
+	var (
+		height = len(rock)
+		width  = len(rock[0])
+		next   = make([][]bool, height)
+	)
+	for y := range next {
+		next[y] = make([]bool, width)
+		for x := range next[y] {
+			if x == 0 || y == 0 || x == width-1 || y == height-1 {
+				next[y][x] = true
+				continue
+			}
+			var walls = 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && rock[y+dy][x+dx] {
+						walls++
+					}
+				}
+			}
+			next[y][x] = walls > 4 || walls == 4 && rock[y][x]
+		}
+	}
+	return next
+}
@@ -0,0 +1,214 @@
+// Package gen builds game levels from a seed. The same seed and Params
+// always give the same level.
+package gen
+
+import (
+	"errors"
+	"experiments/experiments/RPG/game"
+	"fmt"
+	"math/rand"
+)
+
+type Algorithm int
+
+const (
+	// BSP splits the map into rooms joined by corridors,
+	// with closed doors where corridors enter rooms.
+	BSP Algorithm = iota
+	// Caves grows open caverns with a cellular automaton.
+	Caves
+)
+
+// Spawn is one row of the monster table: Monster, a bestiary name, is
+// picked with probability Weight / (sum of all weights).
+type Spawn struct {
+	Weight  int
+	Monster string
+}
+
+var DefaultMonsters = []Spawn{
+	{3, "Rat"},
+	{1, "Spider"},
+}
+
+type Params struct {
+	Name      string
+	Width     int
+	Height    int
+	Algorithm Algorithm
+
+	// BSP
+	MinRoomSize int
+	MaxRoomSize int
+
+	// Caves
+	FillPercent int
+	Smoothing   int
+
+	// Monsters are kinds of Bestiary, which DefaultParams leaves to the
+	// caller
+	Bestiary     game.Bestiary
+	Monsters     []Spawn
+	MonsterCount int
+	// monsters are not placed closer than this to the player
+	SafeRadius int
+
+	UpStairs   bool
+	DownStairs bool
+}
+
+func DefaultParams() Params {
+	return Params{
+		Name:         "Generated",
+		Width:        80,
+		Height:       40,
+		Algorithm:    BSP,
+		MinRoomSize:  5,
+		MaxRoomSize:  12,
+		FillPercent:  45,
+		Smoothing:    5,
+		Monsters:     DefaultMonsters,
+		MonsterCount: 8,
+		SafeRadius:   6,
+	}
+}
+
+var (
+	ErrTooSmall = errors.New("gen: map is too small")
+	ErrNoFloor  = errors.New("gen: generated map has no room for the player")
+)
+
+// Generate builds a level: carve the terrain, pick the player start, clear
+// away whatever isn't reachable from it, then add stairs and monsters.
+func Generate(seed int64, p Params) (*game.Level, error) {
+	if p.Width < 10 || p.Height < 10 {
+		return nil, ErrTooSmall
+	}
+	for _, s := range p.Monsters {
+		if _, ok := p.Bestiary[s.Monster]; !ok {
+			return nil, fmt.Errorf("gen: unknown monster %q", s.Monster)
+		}
+	}
+	var (
+		r     = rand.New(rand.NewSource(seed))
+		level = game.NewLevel(p.Name, p.Width, p.Height)
+		start game.Position
+		ok    bool
+	)
+	switch p.Algorithm {
+	case Caves:
+		start, ok = carveCaves(level, r, p)
+	default:
+		start, ok = carveBSP(level, r, p)
+	}
+	if !ok {
+		return nil, ErrNoFloor
+	}
+	level.SetStart(start)
+
+	var dist = level.Distances(start)
+	fillUnreachable(level, dist)
+	var floor = floorTiles(level, dist)
+
+	if p.UpStairs {
+		level.Map[start.Y][start.X] = game.UpStair
+	}
+	if p.DownStairs {
+		var far = start
+		for _, pos := range floor {
+			if dist[pos] > dist[far] {
+				far = pos
+			}
+		}
+		if far != start {
+			level.Map[far.Y][far.X] = game.DownStair
+		}
+	}
+	placeMonsters(level, r, p, floor, dist)
+	return level, nil
+}
+
+func isFloor(t game.Title) bool {
+	return t == game.DirtFloor
+}
+
+// floorTiles lists reachable floor in reading order, so picking
+// from it with the seeded rand is deterministic.
+func floorTiles(level *game.Level, dist map[game.Position]int) []game.Position {
+	var floor []game.Position
+	for y, row := range level.Map {
+		for x, tile := range row {
+			var pos = game.Position{x, y}
+			if _, reachable := dist[pos]; reachable && isFloor(tile) {
+				floor = append(floor, pos)
+			}
+		}
+	}
+	return floor
+}
+
+// fillUnreachable clears everything the player can't get to
+// and walls in what is left.
+func fillUnreachable(level *game.Level, dist map[game.Position]int) {
+	for y, row := range level.Map {
+		for x := range row {
+			if _, reachable := dist[game.Position{x, y}]; !reachable {
+				level.Map[y][x] = game.Blank
+			}
+		}
+	}
+	wrapWalls(level)
+}
+
+func placeMonsters(level *game.Level, r *rand.Rand, p Params, floor []game.Position, dist map[game.Position]int) {
+	var total = 0
+	for _, s := range p.Monsters {
+		total += s.Weight
+	}
+	if total <= 0 {
+		return
+	}
+	var candidates []game.Position
+	for _, pos := range floor {
+		if dist[pos] > p.SafeRadius {
+			candidates = append(candidates, pos)
+		}
+	}
+	for i := 0; i < p.MonsterCount && len(candidates) > 0; i++ {
+		var (
+			index = r.Intn(len(candidates))
+			pos   = candidates[index]
+			roll  = r.Intn(total)
+		)
+		candidates = append(candidates[:index], candidates[index+1:]...)
+		for _, s := range p.Monsters {
+			if roll < s.Weight {
+				level.Monsters[pos] = p.Bestiary.NewMonster(s.Monster, pos)
+				break
+			}
+			roll -= s.Weight
+		}
+	}
+}
+
+// wrapWalls turns every blank tile touching floor (8-way) into a wall.
+func wrapWalls(level *game.Level) {
+	var height, width = len(level.Map), len(level.Map[0])
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if level.Map[y][x] != game.Blank {
+				continue
+			}
+		neighbors:
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					var nx, ny = x + dx, y + dy
+					if nx >= 0 && ny >= 0 && nx < width && ny < height && isFloor(level.Map[ny][nx]) {
+						level.Map[y][x] = game.StoneWall
+						break neighbors
+					}
+				}
+			}
+		}
+	}
+}
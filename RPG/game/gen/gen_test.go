@@ -0,0 +1,118 @@
+package gen
+
+import (
+	"experiments/experiments/RPG/game"
+	"fmt"
+	"testing"
+)
+
+func testParams(t *testing.T, algorithm Algorithm) Params {
+	t.Helper()
+	bestiary, err := game.StockBestiary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p = DefaultParams()
+	p.Algorithm = algorithm
+	p.Bestiary = bestiary
+	p.UpStairs, p.DownStairs = true, true
+	return p
+}
+
+// draw is the level as text with its monsters, to compare two levels by.
+func draw(level *game.Level) string {
+	var text = fmt.Sprintln(level.Player.Position)
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if m, ok := level.Monsters[game.Position{x, y}]; ok {
+				text += string(m.Rune)
+			} else {
+				text += string(rune(tile))
+			}
+		}
+		text += "\n"
+	}
+	return text
+}
+
+func TestSameSeed(t *testing.T) {
+	for _, algorithm := range []Algorithm{BSP, Caves} {
+		var p = testParams(t, algorithm)
+		var levels = make(map[string]int64)
+		for seed := int64(1); seed <= 5; seed++ {
+			first, err := Generate(seed, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := Generate(seed, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var text = draw(first)
+			if text != draw(second) {
+				t.Errorf("algorithm %d, seed %d made two different levels", algorithm, seed)
+			}
+			if other, ok := levels[text]; ok {
+				t.Errorf("algorithm %d: seeds %d and %d made the same level", algorithm, other, seed)
+			}
+			levels[text] = seed
+		}
+	}
+}
+
+// TestConnected floods each level from the player's start, 4-way and
+// through closed doors, and checks that every floor tile and both stairs
+// are reached.
+func TestConnected(t *testing.T) {
+	for _, algorithm := range []Algorithm{BSP, Caves} {
+		var p = testParams(t, algorithm)
+		for seed := int64(1); seed <= 20; seed++ {
+			level, err := Generate(seed, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var (
+				start   = level.Player.Position
+				reached = map[game.Position]bool{start: true}
+				queue   = []game.Position{start}
+			)
+			for len(queue) > 0 {
+				var pos = queue[0]
+				queue = queue[1:]
+				for _, d := range []game.Position{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+					var next = game.Position{pos.X + d.X, pos.Y + d.Y}
+					if next.Y < 0 || next.Y >= len(level.Map) || next.X < 0 || next.X >= len(level.Map[next.Y]) || reached[next] {
+						continue
+					}
+					if tile := level.Map[next.Y][next.X]; tile != game.StoneWall && tile != game.Blank {
+						reached[next] = true
+						queue = append(queue, next)
+					}
+				}
+			}
+			var stairs = 0
+			for y, row := range level.Map {
+				for x, tile := range row {
+					var pos = game.Position{x, y}
+					switch tile {
+					case game.UpStair, game.DownStair:
+						stairs++
+						fallthrough
+					case game.DirtFloor:
+						if !reached[pos] {
+							t.Errorf("algorithm %d, seed %d: %c at %v can't be reached", algorithm, seed, tile, pos)
+						}
+					}
+				}
+			}
+			if stairs != 2 {
+				t.Errorf("algorithm %d, seed %d: %d stairs, want 2", algorithm, seed, stairs)
+			}
+			for pos := range level.Monsters {
+				if !reached[pos] {
+					t.Errorf("algorithm %d, seed %d: monster at %v can't be reached", algorithm, seed, pos)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package game
+
+type ItemKind int
+
+const (
+	ItemPotion ItemKind = iota
+	ItemWeapon
+	ItemArmor
+)
+
+type Item struct {
+	Entity
+	Kind ItemKind
+	// Attack..MaxAttack is added to the wielder's Strength, Defense is
+	// taken off every hit the wearer takes, Heal is restored by drinking.
+	Attack    int
+	MaxAttack int
+	Defense   int
+	Heal      int
+}
+
+const InventoryCapacity = 10
+
+func NewHealthPotion(pos Position) *Item {
+	return &Item{Entity: Entity{pos, "Health Potion", '!'}, Kind: ItemPotion, Heal: 10}
+}
+
+func NewSword(pos Position) *Item {
+	return &Item{Entity: Entity{pos, "Sword", ')'}, Kind: ItemWeapon, Attack: 3, MaxAttack: 7}
+}
+
+func NewLeatherArmor(pos Position) *Item {
+	return &Item{Entity: Entity{pos, "Leather Armor", '['}, Kind: ItemArmor, Defense: 2}
+}
+
+var itemKinds = map[string]func(Position) *Item{
+	"Health Potion": NewHealthPotion,
+	"Sword":         NewSword,
+	"Leather Armor": NewLeatherArmor,
+}
+
+func (level *Level) addItem(item *Item) {
+	level.Items[item.Position] = append(level.Items[item.Position], item)
+}
+
+// killMonster removes a dead monster, rewards its killer and rolls its
+// loot onto the floor with the combat generator so that drops replay like
+// fights do.
+func (level *Level) killMonster(m *Monster, killer *Player) {
+	delete(level.Monsters, m.Position)
+	killer.gainExperience(m.XP, level)
+	for _, drop := range m.Loot {
+		newItem, ok := itemKinds[drop.Item]
+		if !ok {
+			continue
+		}
+		if drop.Chance >= 100 || level.resolver().RNG.Intn(100) < drop.Chance {
+			level.addItem(newItem(m.Position))
+		}
+	}
+}
+
+// pickup takes the top item under the player if there's room, and
+// reports whether it did.
+func (p *Player) pickup(level *Level) bool {
+	var items = level.Items[p.Position]
+	if len(items) == 0 {
+		return false
+	}
+	if len(p.Inventory) >= p.capacity() {
+		level.publish(NoticeEvent{level.header(p.Position), participant(&p.Character, level), "Inventory Full"})
+		return false
+	}
+	var item = items[len(items)-1]
+	if len(items) == 1 {
+		delete(level.Items, p.Position)
+	} else {
+		level.Items[p.Position] = items[:len(items)-1]
+	}
+	p.Inventory = append(p.Inventory, item)
+	level.publish(ItemEvent{level.header(p.Position), participant(&p.Character, level), PickedUp, item.Name})
+	return true
+}
+
+func (p *Player) capacity() int {
+	if p.Capacity == 0 {
+		return InventoryCapacity
+	}
+	return p.Capacity
+}
+
+func (p *Player) takeItem(index int) *Item {
+	if index < 0 || index >= len(p.Inventory) {
+		return nil
+	}
+	var item = p.Inventory[index]
+	p.Inventory = append(p.Inventory[:index], p.Inventory[index+1:]...)
+	return item
+}
+
+func (p *Player) drop(index int, level *Level) bool {
+	var item = p.takeItem(index)
+	if item == nil {
+		return false
+	}
+	item.Position = p.Position
+	level.addItem(item)
+	level.publish(ItemEvent{level.header(p.Position), participant(&p.Character, level), Dropped, item.Name})
+	return true
+}
+
+// use drinks a potion or equips a weapon or armor, putting what was
+// equipped before back in the inventory. A potion is kept while the
+// player is at full health.
+func (p *Player) use(index int, level *Level) bool {
+	if index < 0 || index >= len(p.Inventory) {
+		return false
+	}
+	var (
+		actor  = participant(&p.Character, level)
+		header = level.header(p.Position)
+	)
+	if p.Inventory[index].Kind == ItemPotion && p.Hitpoints >= p.MaxHitpoints {
+		level.publish(NoticeEvent{header, actor, "Already At Full Health"})
+		return false
+	}
+	var item = p.takeItem(index)
+	switch item.Kind {
+	case ItemPotion:
+		p.Hitpoints += item.Heal
+		if p.Hitpoints > p.MaxHitpoints {
+			p.Hitpoints = p.MaxHitpoints
+		}
+		level.publish(ItemEvent{header, actor, Drank, item.Name})
+		return true
+	case ItemWeapon:
+		if p.Weapon != nil {
+			p.Inventory = append(p.Inventory, p.Weapon)
+		}
+		p.Weapon = item
+	case ItemArmor:
+		if p.Armor != nil {
+			p.Inventory = append(p.Inventory, p.Armor)
+		}
+		p.Armor = item
+	}
+	level.publish(ItemEvent{header, actor, Equipped, item.Name})
+	return true
+}
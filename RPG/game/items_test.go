@@ -0,0 +1,114 @@
+package game
+
+import "testing"
+
+// TestItemCosts checks which item inputs take a turn's energy: only the
+// ones that do something.
+func TestItemCosts(t *testing.T) {
+	var tests = []struct {
+		name      string
+		setup     func(p *Player, level *Level)
+		input     Input
+		energy    float64
+		inventory int
+		hitpoints int
+		notice    string
+	}{
+		{"pickup", func(p *Player, level *Level) {
+			level.addItem(NewSword(p.Position))
+		}, Input{Type: Pickup}, ActionThreshold - Costs.Item, 1, 20, ""},
+		{"nothing to pick up", func(p *Player, level *Level) {}, Input{Type: Pickup}, ActionThreshold, 0, 20, ""},
+		{"inventory full", func(p *Player, level *Level) {
+			p.Capacity = 1
+			p.Inventory = []*Item{NewSword(p.Position)}
+			level.addItem(NewHealthPotion(p.Position))
+		}, Input{Type: Pickup}, ActionThreshold, 1, 20, "Inventory Full"},
+		{"drop", func(p *Player, level *Level) {
+			p.Inventory = []*Item{NewSword(p.Position)}
+		}, Input{Type: Drop}, ActionThreshold - Costs.Item, 0, 20, ""},
+		{"drop nothing", func(p *Player, level *Level) {}, Input{Type: Drop, Item: 2}, ActionThreshold, 0, 20, ""},
+		{"drink", func(p *Player, level *Level) {
+			p.Hitpoints = 5
+			p.Inventory = []*Item{NewHealthPotion(p.Position)}
+		}, Input{Type: Use}, ActionThreshold - Costs.Item, 0, 15, ""},
+		{"drink up to full", func(p *Player, level *Level) {
+			p.Hitpoints = 17
+			p.Inventory = []*Item{NewHealthPotion(p.Position)}
+		}, Input{Type: Use}, ActionThreshold - Costs.Item, 0, 20, ""},
+		{"full health", func(p *Player, level *Level) {
+			p.Inventory = []*Item{NewHealthPotion(p.Position)}
+		}, Input{Type: Use}, ActionThreshold, 1, 20, "Already At Full Health"},
+		{"use nothing", func(p *Player, level *Level) {}, Input{Type: Use, Item: -1}, ActionThreshold, 0, 20, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				world  = testWorld(t, "###", "#@#", "###")
+				s      = NewSimulator(world)
+				player = world.Player
+				level  = world.Level()
+			)
+			player.Hitpoints, player.MaxHitpoints = 20, 20
+			test.setup(player, level)
+			if _, err := s.Step(test.input); err != nil {
+				t.Fatal(err)
+			}
+			if player.ActionPoints != test.energy || len(player.Inventory) != test.inventory || player.Hitpoints != test.hitpoints {
+				t.Errorf("energy %v, %d items, %d hitpoints, want energy %v, %d items, %d hitpoints",
+					player.ActionPoints, len(player.Inventory), player.Hitpoints, test.energy, test.inventory, test.hitpoints)
+			}
+			var notice = ""
+			for _, e := range level.Events.All() {
+				if e, ok := e.(NoticeEvent); ok {
+					notice = e.Text
+				}
+			}
+			if notice != test.notice {
+				t.Errorf("notice %q, want %q", notice, test.notice)
+			}
+		})
+	}
+}
+
+func TestEquip(t *testing.T) {
+	var (
+		world  = testWorld(t, "###", "#@#", "###")
+		player = world.Player
+		level  = world.Level()
+		old    = NewSword(player.Position)
+		sword  = NewSword(player.Position)
+		armor  = NewLeatherArmor(player.Position)
+	)
+	player.Weapon = old
+	player.Inventory = []*Item{sword, armor}
+	if !player.use(0, level) || player.Weapon != sword {
+		t.Fatalf("wielding %v", player.Weapon)
+	}
+	// the old sword went back in the pack, behind the armor
+	if len(player.Inventory) != 2 || player.Inventory[0] != armor || player.Inventory[1] != old {
+		t.Errorf("inventory %v", player.Inventory)
+	}
+	if !player.use(0, level) || player.Armor != armor || len(player.Inventory) != 1 {
+		t.Errorf("wearing %v, inventory %v", player.Armor, player.Inventory)
+	}
+}
+
+func TestLoot(t *testing.T) {
+	var (
+		world  = testWorld(t, "####", "#@S#", "####")
+		level  = world.Level()
+		pos    = Position{2, 1}
+		spider = level.Monsters[pos]
+	)
+	spider.Loot = []LootDrop{{Item: "Sword", Chance: 100}, {Item: "Nothing", Chance: 100}, {Item: "Health Potion", Chance: 0}}
+	level.killMonster(spider, world.Player)
+	if _, alive := level.Monsters[pos]; alive {
+		t.Error("the spider is still there")
+	}
+	if items := level.Items[pos]; len(items) != 1 || items[0].Name != "Sword" || items[0].Position != pos {
+		t.Errorf("dropped %v", items)
+	}
+	if world.Player.Experience != spider.XP {
+		t.Errorf("%d xp for a spider worth %d", world.Player.Experience, spider.XP)
+	}
+}
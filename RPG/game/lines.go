@@ -0,0 +1,99 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineScanner reads the line based files of the game and its uis: maps,
+// bestiaries, atlases and key bindings. They may start with a
+// "<magic> <version>" header and go on with "[section]" headers and
+// "key: value" lines. How the lines add up is up to each parser; the
+// scanner numbers them and reports errors as *MapError.
+type LineScanner struct {
+	File string // for errors
+	Line int    // of Text, from 1
+	Text string // the current line without its line ending
+
+	scanner *bufio.Scanner
+}
+
+func NewLineScanner(r io.Reader, file string) *LineScanner {
+	return &LineScanner{File: file, scanner: bufio.NewScanner(r)}
+}
+
+// Scan moves on to the next line.
+func (s *LineScanner) Scan() bool {
+	if !s.scanner.Scan() {
+		return false
+	}
+	s.Line++
+	s.Text = strings.TrimRight(s.scanner.Text(), "\r")
+	return true
+}
+
+func (s *LineScanner) Err() error {
+	return s.scanner.Err()
+}
+
+// Errorf is a *MapError at column of the current line, 0 for the whole
+// line. Before the first line, say in an empty file, it is line 1.
+func (s *LineScanner) Errorf(column int, format string, args ...interface{}) error {
+	var line = s.Line
+	if line == 0 {
+		line = 1
+	}
+	return &MapError{File: s.File, Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Version reads the current line as a "<magic> <version>" header. It is 0
+// if the line is not a header at all, an error if it has a version that
+// isn't 1 to max.
+func (s *LineScanner) Version(magic string, max int) (int, error) {
+	if !strings.HasPrefix(s.Text, magic) {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(s.Text, magic)))
+	if err != nil {
+		return 0, s.Errorf(0, "bad version in header")
+	}
+	if version < 1 || version > max {
+		return 0, s.Errorf(0, "unsupported %s version %d", strings.TrimPrefix(magic, "rpg"), version)
+	}
+	return version, nil
+}
+
+// Blank is true for empty lines and # comments.
+func (s *LineScanner) Blank() bool {
+	var trimmed = strings.TrimSpace(s.Text)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// Section is the name of a "[name]" line.
+func (s *LineScanner) Section() (name string, ok bool) {
+	var trimmed = strings.TrimSpace(s.Text)
+	if !strings.HasPrefix(trimmed, "[") || !strings.HasSuffix(trimmed, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[1 : len(trimmed)-1]), true
+}
+
+// KeyValue splits a "key: value" line. column is where value starts, for
+// errors about it.
+func (s *LineScanner) KeyValue() (key, value string, column int, err error) {
+	var colon = strings.Index(s.Text, ":")
+	if colon < 0 {
+		return "", "", 0, s.Errorf(0, "expected \"key: value\"")
+	}
+	var rest = strings.TrimLeft(s.Text[colon+1:], " \t")
+	return strings.TrimSpace(s.Text[:colon]), strings.TrimSpace(rest), columnOf(s.Text, rest), nil
+}
+
+// columnOf is the column, in runes, at which tail starts in line.
+func columnOf(line, tail string) int {
+	return utf8.RuneCountInString(line[:len(line)-len(tail)]) + 1
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLineScanner(t *testing.T) {
+	var lines = NewLineScanner(strings.NewReader("rpgtest 2\r\n# comment\n\n[ Sé ]\nkéy→ :  vålue \nno colon\n"), "test")
+	var got []string
+	for lines.Scan() {
+		if lines.Line == 1 {
+			version, err := lines.Version("rpgtest", 2)
+			got = append(got, fmt.Sprint("version ", version, err))
+			continue
+		}
+		if lines.Blank() {
+			got = append(got, "blank")
+			continue
+		}
+		if name, ok := lines.Section(); ok {
+			got = append(got, "section "+name)
+			continue
+		}
+		key, value, column, err := lines.KeyValue()
+		if err != nil {
+			got = append(got, err.Error())
+			continue
+		}
+		got = append(got, fmt.Sprintf("%s=%s@%d", key, value, column))
+	}
+	var want = []string{"version 2 <nil>", "blank", "blank", "section Sé", "kéy→=vålue@9", `test:6: expected "key: value"`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+
+	for text, want := range map[string]string{
+		"rpgtest 3":   "test:1: unsupported test version 3",
+		"rpgtest":     "test:1: bad version in header",
+		"other thing": "<nil>",
+	} {
+		lines = NewLineScanner(strings.NewReader(text), "test")
+		lines.Scan()
+		if _, err := lines.Version("rpgtest", 2); fmt.Sprint(err) != want {
+			t.Errorf("%q: %v, want %s", text, err, want)
+		}
+	}
+}
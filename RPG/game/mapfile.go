@@ -0,0 +1,477 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Map files come in two flavours.
+//
+// Version 0 is a bare ASCII grid (level_1.map) read with the default legend.
+//
+// Version 1 starts with a "rpgmap <version>" line followed by "key: value"
+// metadata and sections:
+//
+//	rpgmap 1
+//	name: Cellar
+//	size: 20x10
+//
+//	[legend]
+//	# tile wall
+//	. tile floor
+//	R monster Rat
+//	> tile down_stairs
+//	@ player
+//	^ trigger trap
+//
+//	[terrain]
+//	#####
+//	#...#
+//	[entities]
+//	 @ R
+//	[triggers]
+//	  ^
+//
+// The terrain layer may also hold entity and trigger glyphs, the tile under
+// them is then guessed from the nearest floor. In the entities and triggers
+// layers a space means "nothing here", and whatever the entities layer
+// places has to stand on a tile it can walk. Inside a layer only a known
+// section name at the start of the line begins another section. Only the
+// first level of a World needs a player; on the others the player arrives
+// by stairs.
+const MapVersion = 1
+
+const mapMagic = "rpgmap"
+
+type LegendKind int
+
+const (
+	LegendTile LegendKind = iota
+	LegendMonster
+	LegendPlayer
+	LegendTrigger
+	LegendItem
+)
+
+var legendKinds = map[string]LegendKind{
+	"tile":    LegendTile,
+	"monster": LegendMonster,
+	"player":  LegendPlayer,
+	"trigger": LegendTrigger,
+	"item":    LegendItem,
+}
+
+func (kind LegendKind) String() string {
+	for name, k := range legendKinds {
+		if k == kind {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+type LegendEntry struct {
+	Kind  LegendKind
+	Value string
+}
+
+var tileNames = map[string]Title{
+	"wall":        StoneWall,
+	"floor":       DirtFloor,
+	"closed_door": CloseDoor,
+	"open_door":   OpenDoor,
+	"blank":       Blank,
+	"up_stairs":   UpStair,
+	"down_stairs": DownStair,
+	"mud":         Mud,
+	"water":       Water,
+}
+
+// stockLegend is the part of the version 0 legend that doesn't depend on
+// the bestiary: the stock tiles, items and the player.
+func stockLegend() map[rune]LegendEntry {
+	return map[rune]LegendEntry{
+		' ':  {LegendTile, "blank"},
+		'\t': {LegendTile, "blank"},
+		'#':  {LegendTile, "wall"},
+		'.':  {LegendTile, "floor"},
+		'|':  {LegendTile, "closed_door"},
+		'/':  {LegendTile, "open_door"},
+		'<':  {LegendTile, "up_stairs"},
+		'>':  {LegendTile, "down_stairs"},
+		',':  {LegendTile, "mud"},
+		'~':  {LegendTile, "water"},
+		'@':  {LegendPlayer, ""},
+		'!':  {LegendItem, "Health Potion"},
+		')':  {LegendItem, "Sword"},
+		'[':  {LegendItem, "Leather Armor"},
+	}
+}
+
+// defaultLegend is the legend of version 0 maps, the stock legend plus
+// every monster glyph of the bestiary.
+func defaultLegend(bestiary Bestiary) map[rune]LegendEntry {
+	var legend = stockLegend()
+	for _, def := range bestiary {
+		if _, taken := legend[def.Glyph]; !taken {
+			legend[def.Glyph] = LegendEntry{LegendMonster, def.Name}
+		}
+	}
+	return legend
+}
+
+// MapError is a problem at a line and column (both 1-based) of a map file.
+// Column is 0 when the whole line is at fault.
+type MapError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *MapError) Error() string {
+	var file = e.File
+	if file == "" {
+		file = "map"
+	}
+	if e.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", file, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", file, e.Line, e.Column, e.Msg)
+}
+
+// mapLayers are the sections that hold rows of glyphs.
+var mapLayers = map[string]bool{"terrain": true, "entities": true, "triggers": true}
+
+func isMapSection(name string) bool {
+	return name == "legend" || mapLayers[name]
+}
+
+type MapLayer struct {
+	Rows  []string
+	Lines []int // file line of every row, for errors
+}
+
+func (layer *MapLayer) add(row string, line int) {
+	layer.Rows = append(layer.Rows, row)
+	layer.Lines = append(layer.Lines, line)
+}
+
+func (layer *MapLayer) trimTrailingBlank() {
+	var n = len(layer.Rows)
+	for n > 0 && strings.TrimSpace(layer.Rows[n-1]) == "" {
+		n--
+	}
+	layer.Rows = layer.Rows[:n]
+	layer.Lines = layer.Lines[:n]
+}
+
+func (layer *MapLayer) width() int {
+	var longest = 0
+	for _, row := range layer.Rows {
+		if n := len([]rune(row)); n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
+
+type MapFile struct {
+	Version  int
+	Name     string
+	Width    int
+	Height   int
+	Meta     map[string]string
+	Legend   map[rune]LegendEntry
+	Terrain  MapLayer
+	Entities MapLayer
+	Triggers MapLayer
+
+	bestiary Bestiary // what the monster glyphs spawn
+}
+
+// ParseMap reads a map file whose monsters are kinds of bestiary.
+func ParseMap(r io.Reader, bestiary Bestiary) (*MapFile, error) {
+	var (
+		lines   = NewLineScanner(r, "")
+		mf      = &MapFile{Meta: make(map[string]string), Legend: defaultLegend(bestiary), bestiary: bestiary}
+		section = ""
+	)
+	for lines.Scan() {
+		if lines.Line == 1 {
+			version, err := lines.Version(mapMagic, MapVersion)
+			if err != nil {
+				return nil, err
+			}
+			if mf.Version = version; version == 0 {
+				section = "terrain"
+				mf.Terrain.add(lines.Text, lines.Line)
+				continue
+			}
+			mf.Legend = map[rune]LegendEntry{
+				' ':  {LegendTile, "blank"},
+				'\t': {LegendTile, "blank"},
+			}
+			continue
+		}
+
+		// a layer row can look like a header, "[  ]" is armour on the
+		// floor, so there only a known name at the left edge is one
+		if name, ok := lines.Section(); ok && mf.Version > 0 && (!mapLayers[section] || isMapSection(name) && strings.HasPrefix(lines.Text, "[")) {
+			if !isMapSection(name) {
+				return nil, lines.Errorf(0, "unknown section %q", name)
+			}
+			section = name
+			continue
+		}
+
+		switch section {
+		case "":
+			if err := mf.parseMeta(lines); err != nil {
+				return nil, err
+			}
+		case "legend":
+			if err := mf.parseLegend(lines); err != nil {
+				return nil, err
+			}
+		case "terrain":
+			mf.Terrain.add(lines.Text, lines.Line)
+		case "entities":
+			mf.Entities.add(lines.Text, lines.Line)
+		case "triggers":
+			mf.Triggers.add(lines.Text, lines.Line)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	mf.Terrain.trimTrailingBlank()
+	mf.Entities.trimTrailingBlank()
+	mf.Triggers.trimTrailingBlank()
+	if len(mf.Terrain.Rows) == 0 {
+		return nil, lines.Errorf(0, "map has no terrain")
+	}
+	if mf.Width == 0 {
+		for _, layer := range []*MapLayer{&mf.Terrain, &mf.Entities, &mf.Triggers} {
+			if w := layer.width(); w > mf.Width {
+				mf.Width = w
+			}
+		}
+	}
+	if mf.Height == 0 {
+		mf.Height = len(mf.Terrain.Rows)
+	}
+	for _, layer := range []*MapLayer{&mf.Terrain, &mf.Entities, &mf.Triggers} {
+		if len(layer.Rows) > mf.Height {
+			return nil, &MapError{Line: layer.Lines[mf.Height], Msg: fmt.Sprintf("map is taller than %d rows", mf.Height)}
+		}
+		for i, row := range layer.Rows {
+			if n := len([]rune(row)); n > mf.Width {
+				return nil, &MapError{Line: layer.Lines[i], Column: mf.Width + 1, Msg: fmt.Sprintf("map is wider than %d columns", mf.Width)}
+			}
+		}
+	}
+	return mf, nil
+}
+
+func (mf *MapFile) parseMeta(lines *LineScanner) error {
+	if lines.Blank() {
+		return nil
+	}
+	key, value, column, err := lines.KeyValue()
+	if err != nil {
+		return err
+	}
+	mf.Meta[key] = value
+	switch key {
+	case "name":
+		mf.Name = value
+	case "size":
+		var w, h int
+		if _, err := fmt.Sscanf(value, "%dx%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+			return lines.Errorf(column, "bad size %q, expected WxH", value)
+		}
+		mf.Width, mf.Height = w, h
+	}
+	return nil
+}
+
+func (mf *MapFile) parseLegend(lines *LineScanner) error {
+	var line = lines.Text
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	var (
+		glyph      = []rune(line)[0]
+		rest       = line[len(string(glyph)):]
+		fields     = strings.Fields(rest)
+		kindColumn = columnOf(line, strings.TrimLeft(rest, " \t"))
+	)
+	if len(fields) == 0 {
+		return lines.Errorf(kindColumn, "legend for %q has no kind", glyph)
+	}
+	kind, ok := legendKinds[fields[0]]
+	if !ok {
+		return lines.Errorf(kindColumn, "unknown legend kind %q", fields[0])
+	}
+	var (
+		entry       = LegendEntry{Kind: kind, Value: strings.Join(fields[1:], " ")}
+		afterKind   = rest[strings.Index(rest, fields[0])+len(fields[0]):]
+		valueColumn = columnOf(line, strings.TrimLeft(afterKind, " \t"))
+	)
+	switch kind {
+	case LegendTile:
+		if _, ok := tileNames[entry.Value]; !ok {
+			return lines.Errorf(valueColumn, "unknown tile %q", entry.Value)
+		}
+	case LegendMonster:
+		if _, ok := mf.bestiary[entry.Value]; !ok {
+			return lines.Errorf(valueColumn, "unknown monster %q", entry.Value)
+		}
+	case LegendItem:
+		if _, ok := itemKinds[entry.Value]; !ok {
+			return lines.Errorf(valueColumn, "unknown item %q", entry.Value)
+		}
+	case LegendTrigger:
+		if entry.Value == "" {
+			return lines.Errorf(valueColumn, "trigger needs a name")
+		}
+	}
+	mf.Legend[glyph] = entry
+	return nil
+}
+
+// Write writes the map in the current format, whatever version it was read
+// in, with its legend in glyph order.
+func (mf *MapFile) Write(w io.Writer) error {
+	var out = bufio.NewWriter(w)
+	fmt.Fprintf(out, "%s %d\n", mapMagic, MapVersion)
+	if mf.Name != "" {
+		fmt.Fprintf(out, "name: %s\n", mf.Name)
+	}
+	fmt.Fprintf(out, "size: %dx%d\n", mf.Width, mf.Height)
+	var keys []string
+	for key := range mf.Meta {
+		if key != "name" && key != "size" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(out, "%s: %s\n", key, mf.Meta[key])
+	}
+
+	var glyphs []rune
+	for glyph, entry := range mf.Legend {
+		// blanks are in every legend
+		if (glyph == ' ' || glyph == '\t') && entry == (LegendEntry{LegendTile, "blank"}) {
+			continue
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	fmt.Fprint(out, "\n[legend]\n")
+	for _, glyph := range glyphs {
+		var entry = mf.Legend[glyph]
+		fmt.Fprintf(out, "%c %s", glyph, entry.Kind)
+		if entry.Value != "" {
+			fmt.Fprintf(out, " %s", entry.Value)
+		}
+		fmt.Fprintln(out)
+	}
+
+	for _, layer := range []struct {
+		name  string
+		layer *MapLayer
+	}{{"terrain", &mf.Terrain}, {"entities", &mf.Entities}, {"triggers", &mf.Triggers}} {
+		if layer.name != "terrain" && len(layer.layer.Rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "\n[%s]\n", layer.name)
+		for _, row := range layer.layer.Rows {
+			fmt.Fprintln(out, row)
+		}
+	}
+	return out.Flush()
+}
+
+// Level builds a fresh Level from the parsed file.
+func (mf *MapFile) Level() (*Level, error) {
+	var level = NewLevel(mf.Name, mf.Width, mf.Height)
+
+	var place = func(entry LegendEntry, pos Position) {
+		switch entry.Kind {
+		case LegendPlayer:
+			level.SetStart(pos)
+		case LegendMonster:
+			level.Monsters[pos] = mf.bestiary.NewMonster(entry.Value, pos)
+		case LegendTrigger:
+			level.Triggers[pos] = entry.Value
+		case LegendItem:
+			level.addItem(itemKinds[entry.Value](pos))
+		}
+	}
+
+	for y, row := range mf.Terrain.Rows {
+		for x, c := range []rune(row) {
+			entry, ok := mf.Legend[c]
+			if !ok {
+				return nil, &MapError{Line: mf.Terrain.Lines[y], Column: x + 1, Msg: fmt.Sprintf("invalid character %q in terrain", c)}
+			}
+			if entry.Kind == LegendTile {
+				level.Map[y][x] = tileNames[entry.Value]
+			} else {
+				level.Map[y][x] = Pending
+				place(entry, Position{x, y})
+			}
+		}
+	}
+
+	var overlays = []struct {
+		layer *MapLayer
+		name  string
+		kinds []LegendKind
+	}{
+		{&mf.Entities, "entities", []LegendKind{LegendPlayer, LegendMonster, LegendItem}},
+		{&mf.Triggers, "triggers", []LegendKind{LegendTrigger}},
+	}
+	for _, overlay := range overlays {
+		for y, row := range overlay.layer.Rows {
+			for x, c := range []rune(row) {
+				if c == ' ' || c == '\t' {
+					continue
+				}
+				entry, ok := mf.Legend[c]
+				if !ok || !hasKind(overlay.kinds, entry.Kind) {
+					return nil, &MapError{Line: overlay.layer.Lines[y], Column: x + 1, Msg: fmt.Sprintf("invalid character %q in %s", c, overlay.name)}
+				}
+				if entry.Kind != LegendTrigger && !canWalk(level, Position{x, y}) {
+					return nil, &MapError{Line: overlay.layer.Lines[y], Column: x + 1, Msg: fmt.Sprintf("%s %q is not on a floor", entry.Kind, c)}
+				}
+				place(entry, Position{x, y})
+			}
+		}
+	}
+
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile == Pending {
+				level.Map[y][x] = level.bfsFloor(Position{x, y})
+			}
+		}
+	}
+	return level, nil
+}
+
+func hasKind(kinds []LegendKind, kind LegendKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseMapFile(t *testing.T, data []byte) *MapFile {
+	t.Helper()
+	mf, err := ParseMap(bytes.NewReader(data), stockBestiary(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mf
+}
+
+// TestMapRoundTrip writes the stock maps and reads them back: the
+// written file has to build the same level, and write the same again.
+func TestMapRoundTrip(t *testing.T) {
+	for _, path := range []string{"maps/level_1.map", "maps/entrance.map", "maps/level_2.map"} {
+		t.Run(path, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var (
+				original = parseMapFile(t, data)
+				written  bytes.Buffer
+				again    bytes.Buffer
+			)
+			if err := original.Write(&written); err != nil {
+				t.Fatal(err)
+			}
+			var copied = parseMapFile(t, written.Bytes())
+			if err := copied.Write(&again); err != nil {
+				t.Fatal(err)
+			}
+			if written.String() != again.String() {
+				t.Errorf("written twice differently:\n%s\n%s", written.String(), again.String())
+			}
+
+			if copied.Version != MapVersion || copied.Name != original.Name || copied.Width != original.Width || copied.Height != original.Height {
+				t.Errorf("read back %q %dx%d version %d, want %q %dx%d", copied.Name, copied.Width, copied.Height, copied.Version, original.Name, original.Width, original.Height)
+			}
+			if !reflect.DeepEqual(copied.Legend, original.Legend) {
+				t.Errorf("legend %v, want %v", copied.Legend, original.Legend)
+			}
+			for _, layers := range [][2]MapLayer{{copied.Terrain, original.Terrain}, {copied.Entities, original.Entities}, {copied.Triggers, original.Triggers}} {
+				if strings.Join(layers[0].Rows, "\n") != strings.Join(layers[1].Rows, "\n") {
+					t.Errorf("rows\n%s\nwant\n%s", strings.Join(layers[0].Rows, "\n"), strings.Join(layers[1].Rows, "\n"))
+				}
+			}
+
+			want, err := original.Level()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := copied.Level()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Map, want.Map) || got.start != want.start || len(got.Monsters) != len(want.Monsters) || len(got.Items) != len(want.Items) {
+				t.Error("the written map builds a different level")
+			}
+			for pos, m := range want.Monsters {
+				if other, ok := got.Monsters[pos]; !ok || other.Name != m.Name {
+					t.Errorf("no %s at %v", m.Name, pos)
+				}
+			}
+		})
+	}
+}
+
+// TestMapSections checks that a layer row that looks like a header is a
+// row, and that a known section still ends the layer.
+func TestMapSections(t *testing.T) {
+	var mf = parseMapFile(t, []byte(`rpgmap 1
+
+[legend]
+# tile wall
+. tile floor
+[ item Leather Armor
+] item Sword
+
+[terrain]
+#####
+#...#
+#####
+[entities]
+
+ [ ]
+ [triggers]
+[triggers]
+`))
+	if want := []string{"", " [ ]", " [triggers]"}; !reflect.DeepEqual(mf.Entities.Rows, want) {
+		t.Errorf("entities %q, want %q", mf.Entities.Rows, want)
+	}
+	if len(mf.Triggers.Rows) != 0 {
+		t.Errorf("triggers %q, want none", mf.Triggers.Rows)
+	}
+}
+
+func TestMapErrors(t *testing.T) {
+	const header = "rpgmap 1\nname: Test\n\n[legend]\n# tile wall\n"
+	var tests = []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"version", "rpgmap 2\n", "map:1: unsupported map version 2"},
+		{"bad header", "rpgmap one\n", "map:1: bad version in header"},
+		{"size", "rpgmap 1\nsize: big\n", `map:2:7: bad size "big", expected WxH`},
+		{"size after spaces", "rpgmap 1\nname: x\nsize:  0x3\n", `map:3:8: bad size "0x3", expected WxH`},
+		{"section", "rpgmap 1\n[monsters]\n", `map:2: unknown section "monsters"`},
+		{"kind", header + "~ lake\n", `map:6:3: unknown legend kind "lake"`},
+		{"no kind", header + "~\n", `map:6:2: legend for '~' has no kind`},
+		{"tile", header + ". tile  lava\n", `map:6:9: unknown tile "lava"`},
+		{"wide glyph", header + "é tile lava\n", `map:6:8: unknown tile "lava"`},
+		{"wide glyph monster", header + "→  monster Dragon\n", `map:6:12: unknown monster "Dragon"`},
+		{"item", header + "* item Gold\n", `map:6:8: unknown item "Gold"`},
+		{"trigger", header + "^ trigger\n", "map:6:10: trigger needs a name"},
+		{"no terrain", header, "map:5: map has no terrain"},
+		{"too wide", "rpgmap 1\nsize: 3x1\n\n[legend]\n# tile wall\n\n[terrain]\n####\n", "map:8:4: map is wider than 3 columns"},
+		{"too tall", "rpgmap 1\nsize: 2x1\n\n[legend]\n# tile wall\n\n[terrain]\n##\n##\n", "map:9: map is taller than 1 rows"},
+		{"v0 terrain", "#####\n#.@é#\n", `map:2:4: invalid character 'é' in terrain`},
+		{"entities", header + "\n[terrain]\n###\n[entities]\n  #\n", `map:10:3: invalid character '#' in entities`},
+		{"monster on a wall", header + "R monster Rat\n. tile floor\n\n[terrain]\n#.#\n[entities]\n R R\n", `map:12:4: monster 'R' is not on a floor`},
+		{"item on a wall", header + "! item Health Potion\n\n[terrain]\n#\n[entities]\n!\n", `map:11:1: item '!' is not on a floor`},
+		{"player on a wall", header + "@ player\n\n[terrain]\n#\n#\n[entities]\n\n@\n", `map:13:1: player '@' is not on a floor`},
+		{"section in a layer", header + "\n[terrain]\n###\n[rooms]\n", `map:9:1: invalid character '[' in terrain`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mf, err := ParseMap(strings.NewReader(test.text), stockBestiary(t))
+			if err == nil {
+				_, err = mf.Level()
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
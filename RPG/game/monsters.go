@@ -1,67 +1,64 @@
 package game
 
-import "fmt"
-
 type Monster struct {
 	Character
+	// AI names an entry of AIProfiles
+	AI    string
+	State AIState
+	// Noticed is set once the monster has seen the player, it then
+	// heads for LastSeen until it catches up or loses track.
+	Noticed    bool
+	LastSeen   Position
+	Home       Position
+	Route      []Position
+	RouteIndex int
+	// Loot is rolled for on death, XP goes to the player who killed it
+	Loot []LootDrop
+	XP   int
 }
 
-func NewRat(pos Position) *Monster {
-	return &Monster{Character{
-		Entity: Entity{
-			Position: pos,
-			Name:     "Rat",
-			Rune:     'R',
-		},
-		Hitpoints:    500,
-		Strength:     0,
-		Speed:        1.5,
-		ActionPoints: 0.0,
-	}}
-}
-
-func NewSpider(pos Position) *Monster {
-	return &Monster{Character{
-		Entity: Entity{
-			Position: pos,
-			Name:     "Spider",
-			Rune:     'S',
-		},
-		Hitpoints:    1000,
-		Strength:     0,
-		Speed:        1.0,
-		ActionPoints: 0.0,
-	}}
-}
-
-func (m *Monster) Update(level *Level) {
-	m.ActionPoints += m.Speed
-	var (
-		playerPos = level.Player.Position
-		pos       = level.astar(m.Position, playerPos)
-	)
-
-	var movIndex = 1
-	for i := 0; i < int(m.ActionPoints); i++ {
-		// Most be > 1 because 1st position is the monsters current
-		if movIndex < len(pos) {
-			m.Move(pos[movIndex], level)
-			movIndex++
-			m.ActionPoints--
+// Act asks the AI for one action, carries it out and returns its cost.
+// The scheduler decides when the monster gets to act.
+func (m *Monster) Act(level *Level) float64 {
+	var action = m.ai().Act(m, level)
+	switch action.Kind {
+	case ActMove:
+		if level.playerAt(action.To) != nil {
+			m.Move(action.To, level)
+			return Costs.Attack
+		}
+		if openDoor(level, action.To, &m.Character) {
+			return Costs.OpenDoor
+		}
+		var cost = Costs.Move * terrainCost(level, action.To)
+		m.Move(action.To, level)
+		return cost
+	case ActShoot:
+		var player = level.playerAt(action.To)
+		if player == nil {
+			break
 		}
+		level.shoot(&m.Character, &player.Character)
+		level.checkDeath(&m.Character, &player.Character)
+		return Costs.Attack
 	}
+	return Costs.Wait
 }
 
 func (m *Monster) Move(pos Position, level *Level) {
-	if _, ok := level.Monsters[pos]; !ok && pos != level.Player.Position {
+	var target = level.playerAt(pos)
+	if _, ok := level.Monsters[pos]; !ok && target == nil {
+		var from = m.Position
 		delete(level.Monsters, m.Position)
 		level.Monsters[pos] = m
 		m.Position = pos
-	} else {
-		level.AddEvent(fmt.Sprintf("%s Attacks %d Player !", m.Name, m.Strength))
-		Attack(m, level.Player)
-		if m.Hitpoints <= 0 {
-			delete(level.Monsters, m.Position)
+		level.publish(MoveEvent{level.header(pos), participant(&m.Character, level), from})
+	} else if target != nil {
+		var player = &target.Character
+		level.fight(&m.Character, player)
+		level.checkDeath(&m.Character, player)
+		if level.checkDeath(player, &m.Character) {
+			level.killMonster(m, target)
 		}
 	}
 }
@@ -0,0 +1,174 @@
+package game
+
+import "fmt"
+
+// setParty hands the party to the world and every level in it.
+func (world *World) setParty(players []*Player) {
+	world.Player, world.Players = players[0], players
+	for _, level := range world.Levels {
+		level.Player, level.Players = players[0], players
+	}
+}
+
+// AddPlayer puts a fresh player into the party on the current level, as
+// close to the first one as there's room, and names them after the
+// first with a number.
+func (world *World) AddPlayer() *Player {
+	var (
+		level = world.Level()
+		p     = newPlayer()
+		taken = make(map[Position]bool)
+		names = make(map[string]bool)
+	)
+	for _, other := range world.Players {
+		taken[other.Position] = true
+		names[other.Name] = true
+	}
+	p.Position = level.freeSpot(world.Player.Position, taken)
+	for n := 2; ; n++ {
+		if name := fmt.Sprintf("%s %d", world.Player.Name, n); !names[name] {
+			p.Name = name
+			break
+		}
+	}
+	world.setParty(append(world.Players[:len(world.Players):len(world.Players)], p))
+	level.updateVision()
+	return p
+}
+
+// RemovePlayer takes p out of the party, unless they're all that's left.
+// If p was first whoever comes next becomes World.Player.
+func (world *World) RemovePlayer(p *Player) bool {
+	var party = make([]*Player, 0, len(world.Players))
+	for _, other := range world.Players {
+		if other != p {
+			party = append(party, other)
+		}
+	}
+	if len(party) == 0 || len(party) == len(world.Players) {
+		return false
+	}
+	world.setParty(party)
+	world.Level().updateVision()
+	return true
+}
+
+// playerIndex is p's place in the party, -1 if they're not in it.
+func (world *World) playerIndex(p *Player) int {
+	for i, other := range world.Players {
+		if other == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// playerAt is the living player at pos, nil if there's none.
+func (level *Level) playerAt(pos Position) *Player {
+	for _, p := range level.Players {
+		if p.Hitpoints > 0 && p.Position == pos {
+			return p
+		}
+	}
+	return nil
+}
+
+// nearestPlayer is the living player closest to pos, the first one in
+// the party on a tie or if they're all dead.
+func (level *Level) nearestPlayer(pos Position) *Player {
+	var nearest = level.Player
+	var best = -1
+	for _, p := range level.Players {
+		if d := level.distance(pos, p.Position); p.Hitpoints > 0 && (best < 0 || d < best) {
+			nearest, best = p, d
+		}
+	}
+	return nearest
+}
+
+func (level *Level) partyAlive() bool {
+	for _, p := range level.Players {
+		if p.Hitpoints > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (level *Level) isPlayer(c *Character) bool {
+	for _, p := range level.Players {
+		if c == &p.Character {
+			return true
+		}
+	}
+	return false
+}
+
+// seat is a view that joined and the player it plays.
+type seat struct {
+	view   chan *Snapshot
+	player *Player
+}
+
+func (game *Game) seatOf(view chan *Snapshot) *seat {
+	for i := range game.seats {
+		if game.seats[i].view == view {
+			return &game.seats[i]
+		}
+	}
+	return nil
+}
+
+// playerFor is who plays the inputs of view.
+func (game *Game) playerFor(view chan *Snapshot) *Player {
+	if seat := game.seatOf(view); seat != nil {
+		return seat.player
+	}
+	return game.World.Player
+}
+
+// join opens view as a window that plays the first player if nobody
+// does yet, a new one if somebody does.
+func (game *Game) join(view chan *Snapshot) {
+	if game.seatOf(view) != nil {
+		return
+	}
+	var player = game.World.Player
+	if len(game.seats) > 0 {
+		game.record(replayEntry{Join: true})
+		player = game.World.AddPlayer()
+	}
+	game.seats = append(game.seats, seat{view, player})
+	game.openWindow(view)
+}
+
+// leave takes the player of view out of the game if it joined. The last
+// player stays, for whoever joins next.
+func (game *Game) leave(view chan *Snapshot) {
+	for i, seat := range game.seats {
+		if seat.view != view {
+			continue
+		}
+		game.seats = append(game.seats[:i], game.seats[i+1:]...)
+		game.record(replayEntry{Player: game.World.playerIndex(seat.player), Leave: true})
+		game.dropPlayer(seat.player)
+		return
+	}
+}
+
+// dropPlayer takes p out of the party and the travels, the game is over
+// if that leaves nobody alive.
+func (game *Game) dropPlayer(p *Player) {
+	delete(game.Simulator.travels, p)
+	if game.World.RemovePlayer(p) && !game.Level().partyAlive() {
+		game.Simulator.GameOver = true
+	}
+}
+
+// reseat hands the seats their players again, in party order, after the
+// world was replaced.
+func (game *Game) reseat() {
+	for i := range game.seats {
+		game.seats[i].player = game.World.Players[i]
+	}
+}
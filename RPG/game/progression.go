@@ -0,0 +1,97 @@
+package game
+
+// Progression is the experience curve and what a level-up is worth.
+// Reaching level n takes BaseXP * (1 + 2 + ... + n-1) experience in total.
+// Everything here is a pure function of its arguments. SpeedPerLevel
+// should be a power of two like 1/16: speeds add up exactly then, level
+// by level or all at once, and so does the energy they earn.
+type Progression struct {
+	BaseXP            int
+	MaxLevel          int
+	HitpointsPerLevel int
+	StrengthPerLevel  int
+	SpeedPerLevel     float64
+}
+
+var DefaultProgression = Progression{
+	BaseXP:            20,
+	MaxLevel:          20,
+	HitpointsPerLevel: 5,
+	StrengthPerLevel:  2,
+	SpeedPerLevel:     1.0 / 16,
+}
+
+// Threshold is the total experience needed to reach level.
+func (p Progression) Threshold(level int) int {
+	if level <= 1 {
+		return 0
+	}
+	return p.BaseXP * level * (level - 1) / 2
+}
+
+// LevelFor is the level that xp experience buys.
+func (p Progression) LevelFor(xp int) int {
+	if p.BaseXP <= 0 {
+		return 1
+	}
+	var level = 1
+	for (p.MaxLevel == 0 || level < p.MaxLevel) && xp >= p.Threshold(level+1) {
+		level++
+	}
+	return level
+}
+
+// Progress is how far xp is into its level and how much experience the
+// level spans; span is 0 at MaxLevel.
+func (p Progression) Progress(xp int) (into, span int) {
+	var level = p.LevelFor(xp)
+	into = xp - p.Threshold(level)
+	if p.MaxLevel != 0 && level >= p.MaxLevel {
+		return into, 0
+	}
+	return into, p.Threshold(level+1) - p.Threshold(level)
+}
+
+type Growth struct {
+	Hitpoints int
+	Strength  int
+	Speed     float64
+}
+
+// Growth is what going from level from to level to adds to the stats.
+func (p Progression) Growth(from, to int) Growth {
+	if to <= from {
+		return Growth{}
+	}
+	var n = to - from
+	return Growth{n * p.HitpointsPerLevel, n * p.StrengthPerLevel, float64(n) * p.SpeedPerLevel}
+}
+
+// Apply returns c grown by g. The hitpoints gained are healed as well,
+// the damage already taken stays.
+func (g Growth) Apply(c Character) Character {
+	c.MaxHitpoints += g.Hitpoints
+	c.Hitpoints += g.Hitpoints
+	c.Strength += g.Strength
+	c.Speed += g.Speed
+	return c
+}
+
+// gainExperience adds amount to the player's experience and levels up
+// as far as it reaches.
+func (p *Player) gainExperience(amount int, level *Level) {
+	if amount <= 0 {
+		return
+	}
+	var (
+		actor  = participant(&p.Character, level)
+		header = level.header(p.Position)
+	)
+	p.Experience += amount
+	level.publish(ExperienceEvent{header, actor, amount, p.Experience})
+	if next := DefaultProgression.LevelFor(p.Experience); next > p.Level {
+		p.Character = DefaultProgression.Growth(p.Level, next).Apply(p.Character)
+		p.Level = next
+		level.publish(LevelUpEvent{header, actor, next})
+	}
+}
@@ -0,0 +1,95 @@
+package game
+
+import "testing"
+
+func TestLevelFor(t *testing.T) {
+	var p = Progression{BaseXP: 20, MaxLevel: 5}
+	var tests = []struct {
+		xp, level, into, span int
+	}{
+		{0, 1, 0, 20},
+		{19, 1, 19, 20},
+		{20, 2, 0, 40},
+		{59, 2, 39, 40},
+		{60, 3, 0, 60},
+		{200, 5, 0, 0},
+		{10000, 5, 9800, 0},
+	}
+	for _, test := range tests {
+		var level = p.LevelFor(test.xp)
+		into, span := p.Progress(test.xp)
+		if level != test.level || into != test.into || span != test.span {
+			t.Errorf("%d xp: level %d, %d of %d, want level %d, %d of %d", test.xp, level, into, span, test.level, test.into, test.span)
+		}
+		if level > 1 && test.xp < p.Threshold(level) || level < p.MaxLevel && test.xp >= p.Threshold(level+1) {
+			t.Errorf("%d xp is not between the thresholds of level %d", test.xp, level)
+		}
+	}
+	if level := (Progression{}).LevelFor(1000); level != 1 {
+		t.Errorf("no curve gives level %d", level)
+	}
+	if level := (Progression{BaseXP: 1}).LevelFor(1000); level != 45 {
+		t.Errorf("no MaxLevel gives level %d for 1000 xp, want 45", level)
+	}
+}
+
+func TestGrowth(t *testing.T) {
+	var p = DefaultProgression
+	if g := p.Growth(3, 3); g != (Growth{}) {
+		t.Errorf("growth without a level-up: %+v", g)
+	}
+	if g := p.Growth(4, 2); g != (Growth{}) {
+		t.Errorf("growth going down: %+v", g)
+	}
+	if g := p.Growth(1, 4); g != (Growth{3 * p.HitpointsPerLevel, 3 * p.StrengthPerLevel, 3 * p.SpeedPerLevel}) {
+		t.Errorf("growth from 1 to 4: %+v", g)
+	}
+
+	// level by level ends exactly where all at once does
+	var (
+		start   = Character{Hitpoints: 7, MaxHitpoints: 10, Strength: 1, Speed: 1.5}
+		stepped = start
+	)
+	for level := 1; level < p.MaxLevel; level++ {
+		stepped = p.Growth(level, level+1).Apply(stepped)
+	}
+	var once = p.Growth(1, p.MaxLevel).Apply(start)
+	if stepped != once {
+		t.Errorf("level by level %+v, at once %+v", stepped, once)
+	}
+	var gained = (p.MaxLevel - 1) * p.HitpointsPerLevel
+	if once.MaxHitpoints != 10+gained || once.Hitpoints != 7+gained {
+		t.Errorf("hitpoints %d of %d, the damage taken should stay", once.Hitpoints, once.MaxHitpoints)
+	}
+}
+
+func TestGainExperience(t *testing.T) {
+	var (
+		world  = testWorld(t, "###", "#@#", "###")
+		player = world.Player
+		level  = world.Level()
+		before = player.Character
+	)
+	player.gainExperience(DefaultProgression.Threshold(3)+1, level)
+	if player.Level != 3 || player.Experience != DefaultProgression.Threshold(3)+1 {
+		t.Errorf("level %d with %d xp", player.Level, player.Experience)
+	}
+	if player.Speed != before.Speed+2*DefaultProgression.SpeedPerLevel || player.MaxHitpoints != before.MaxHitpoints+2*DefaultProgression.HitpointsPerLevel {
+		t.Errorf("grew to %+v", player.Character)
+	}
+	var events = level.Events.All()
+	if len(events) != 2 {
+		t.Fatalf("events %v", events)
+	}
+	if e, ok := events[0].(ExperienceEvent); !ok || e.Total != player.Experience {
+		t.Errorf("first event %#v", events[0])
+	}
+	if e, ok := events[1].(LevelUpEvent); !ok || e.Level != 3 {
+		t.Errorf("second event %#v", events[1])
+	}
+
+	player.gainExperience(0, level)
+	if level.Events.Len() != 2 {
+		t.Error("gained nothing but said so")
+	}
+}
@@ -0,0 +1,459 @@
+package game
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReplayVersion is written into every replay. Replays of older versions
+// are refused: their final hash took in the save version, so they can't
+// be verified any more.
+const ReplayVersion = 3
+
+var (
+	ErrReplayMismatch   = errors.New("game: replay didn't end in the recorded state")
+	ErrReplayUnfinished = errors.New("game: replay has no final state to compare with")
+	ErrReplayStopped    = errors.New("game: replay stopped before the end")
+	ErrOldReplay        = errors.New("game: replay is from an older version of the game")
+)
+
+// A replay is JSON, one value per line: a replayHeader, a replayEntry for
+// every input Run acted on, every travel step it took by itself and every
+// player who joined or left, and a last entry with the hash of the final
+// state. A recording cut short, by a crash say, has no last entry but
+// still replays.
+type replayHeader struct {
+	Version  int         `json:"version"`
+	Seed     uint64      `json:"seed"`
+	Diagonal bool        `json:"diagonal,omitempty"`
+	Maps     []replayMap `json:"maps"`
+	// Bestiary is the bestiaryHash of the game's bestiary
+	Bestiary string `json:"bestiary"`
+}
+
+// replayMap names a level file and what was in it, a replay is only
+// good for the same maps.
+type replayMap struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+type replayEntry struct {
+	// Time is in ms since the recording started
+	Time int64 `json:"t"`
+	// Player is who played the input or left, by their place in the party
+	Player int `json:"player,omitempty"`
+	inputJSON
+	// Continue is a step of the travels, Join a player added to the
+	// party and Leave Player taken out of it
+	Continue bool `json:"continue,omitempty"`
+	Join     bool `json:"join,omitempty"`
+	Leave    bool `json:"leave,omitempty"`
+	// Hash is the StateHash at the end, in the last entry only
+	Hash string `json:"hash,omitempty"`
+}
+
+var inputNames = map[InputType]string{
+	None:      "wait",
+	Up:        "up",
+	Down:      "down",
+	Left:      "left",
+	Right:     "right",
+	UpLeft:    "up_left",
+	UpRight:   "up_right",
+	DownLeft:  "down_left",
+	DownRight: "down_right",
+	Explore:   "explore",
+	Travel:    "travel",
+	Pickup:    "pickup",
+	Drop:      "drop",
+	Use:       "use",
+	Restart:   "restart",
+}
+
+// inputJSON is an Input the way replays and MarshalJSON write it, by
+// name. Inputs without one, those about views, aren't written.
+type inputJSON struct {
+	Input  string    `json:"input,omitempty"`
+	Item   int       `json:"item,omitempty"`
+	Target *Position `json:"target,omitempty"`
+}
+
+func inputEntry(input Input) replayEntry {
+	return replayEntry{inputJSON: toInputJSON(input)}
+}
+
+func toInputJSON(input Input) inputJSON {
+	var e = inputJSON{Input: inputNames[input.Type], Item: input.Item}
+	if input.Type == Travel {
+		var target = input.Target
+		e.Target = &target
+	}
+	return e
+}
+
+func (e inputJSON) input() (Input, error) {
+	for t, name := range inputNames {
+		if name == e.Input {
+			var input = Input{Type: t, Item: e.Item}
+			if e.Target != nil {
+				input.Target = *e.Target
+			}
+			return input, nil
+		}
+	}
+	return Input{}, fmt.Errorf("game: unknown input %q", e.Input)
+}
+
+// MarshalJSON writes the turn actions, Travel, Explore and Restart by
+// name, for sending them over the network. The others are an error.
+func (input Input) MarshalJSON() ([]byte, error) {
+	if _, ok := inputNames[input.Type]; !ok {
+		return nil, fmt.Errorf("game: input %d has no name", input.Type)
+	}
+	return json.Marshal(toInputJSON(input))
+}
+
+func (input *Input) UnmarshalJSON(data []byte) error {
+	var e inputJSON
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	decoded, err := e.input()
+	if err != nil {
+		return err
+	}
+	*input = decoded
+	return nil
+}
+
+// StateHash is a SHA-256 of everything Save writes but the save version,
+// in hex. Games that played out the same have the same hash.
+func (game *Game) StateHash() (string, error) {
+	file, err := game.saveFile()
+	if err != nil {
+		return "", err
+	}
+	var hash = sha256.New()
+	if err := json.NewEncoder(hash).Encode(file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// bestiaryHash is a SHA-256 of the monster definitions in hex, sprites
+// left out as they don't change how a game plays.
+func bestiaryHash(bestiary Bestiary) (string, error) {
+	var defs = make(map[string]MonsterDef, len(bestiary))
+	for name, def := range bestiary {
+		var d = *def
+		d.Sprite = Sprite{}
+		defs[name] = d
+	}
+	data, err := json.Marshal(defs)
+	if err != nil {
+		return "", err
+	}
+	var sum = sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type recorder struct {
+	encoder *json.Encoder
+	start   time.Time
+	err     error
+}
+
+// Record writes a replay of the game to w as Run plays it: the combat
+// seed, the maps, every input and who played it and, once Run returns,
+// the final state hash. It must be called before the first turn. If
+// writing fails the recording stops and Run returns the error.
+func (game *Game) Record(w io.Writer) error {
+	if game.Simulator.Turn != 0 {
+		return errors.New("game: can only record from the first turn")
+	}
+	rng, ok := game.World.Combat.RNG.(*SeededRNG)
+	if !ok {
+		return errors.New("game: can only record with a SeededRNG")
+	}
+	bestiary, err := bestiaryHash(game.Bestiary)
+	if err != nil {
+		return err
+	}
+	var header = replayHeader{Version: ReplayVersion, Seed: rng.State, Diagonal: game.World.Diagonal, Bestiary: bestiary}
+	for _, path := range game.paths {
+		sum, err := fileHash(path)
+		if err != nil {
+			return err
+		}
+		header.Maps = append(header.Maps, replayMap{path, sum})
+	}
+	var r = &recorder{encoder: json.NewEncoder(w), start: time.Now()}
+	if err := r.encoder.Encode(header); err != nil {
+		return err
+	}
+	game.recorder = r
+	return nil
+}
+
+func (game *Game) record(e replayEntry) {
+	var r = game.recorder
+	if r == nil || r.err != nil {
+		return
+	}
+	e.Time = int64(time.Since(r.start) / time.Millisecond)
+	if err := r.encoder.Encode(e); err != nil {
+		r.err = fmt.Errorf("game: recording stopped: %w", err)
+	}
+}
+
+// recordInput records an input of the player of view.
+func (game *Game) recordInput(view chan *Snapshot, input Input) {
+	var e = inputEntry(input)
+	e.Player = game.World.playerIndex(game.playerFor(view))
+	game.record(e)
+}
+
+// finishRecording writes the final state hash and returns what went
+// wrong with the recording, if anything.
+func (game *Game) finishRecording() error {
+	var r = game.recorder
+	if r == nil {
+		return nil
+	}
+	if r.err == nil {
+		if hash, err := game.StateHash(); err != nil {
+			r.err = fmt.Errorf("game: recording stopped: %w", err)
+		} else {
+			game.record(replayEntry{Hash: hash})
+		}
+	}
+	game.recorder = nil
+	return r.err
+}
+
+func fileHash(path string) (string, error) {
+	file, err := OpenAsset(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	var hash = sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Replay is a recording read back by ReadReplay.
+type Replay struct {
+	Seed     uint64
+	Diagonal bool
+	Paths    []string
+	maps     []replayMap
+	bestiary string
+	entries  []replayEntry
+	hash     string // "" if the recording was cut short
+}
+
+func ReadReplay(r io.Reader) (*Replay, error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		header  replayHeader
+		replay  = &Replay{}
+	)
+	scanner.Buffer(nil, 1<<20)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("game: replay is empty")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("game: reading replay: %w", err)
+	}
+	if header.Version >= 1 && header.Version < ReplayVersion {
+		return nil, ErrOldReplay
+	}
+	if header.Version != ReplayVersion {
+		return nil, fmt.Errorf("game: unsupported replay version %d", header.Version)
+	}
+	replay.Seed, replay.Diagonal, replay.maps, replay.bestiary = header.Seed, header.Diagonal, header.Maps, header.Bestiary
+	for _, m := range header.Maps {
+		replay.Paths = append(replay.Paths, m.Path)
+	}
+	for line := 2; scanner.Scan(); line++ {
+		var e replayEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("game: reading replay line %d: %w", line, err)
+		}
+		if e.Hash != "" {
+			replay.hash = e.Hash
+			break
+		}
+		if !e.Continue && !e.Join && !e.Leave {
+			if _, err := e.input(); err != nil {
+				return nil, err
+			}
+		}
+		replay.entries = append(replay.entries, e)
+	}
+	return replay, scanner.Err()
+}
+
+// NewGame starts the recorded game over with numWindows level channels,
+// provided the maps and bestiary are still what they were.
+func (r *Replay) NewGame(numWindows int, bestiary Bestiary) (*Game, error) {
+	sum, err := bestiaryHash(bestiary)
+	if err != nil {
+		return nil, err
+	}
+	if sum != r.bestiary {
+		return nil, errors.New("game: the bestiary has changed since the replay was recorded")
+	}
+	for _, m := range r.maps {
+		sum, err := fileHash(m.Path)
+		if err != nil {
+			return nil, err
+		}
+		if sum != m.SHA256 {
+			return nil, fmt.Errorf("game: %s has changed since the replay was recorded", m.Path)
+		}
+	}
+	game, err := NewGame(numWindows, bestiary, r.Paths...)
+	if err != nil {
+		return nil, err
+	}
+	game.World.SetCombat(NewResolver(game.World.Combat.Rules, &SeededRNG{r.Seed}))
+	game.World.SetDiagonal(r.Diagonal)
+	return game, nil
+}
+
+// apply does what Run did for an entry. Step's errors are dropped like
+// Run drops them, they didn't change the game then either.
+func (game *Game) apply(e replayEntry) error {
+	switch {
+	case e.Continue:
+		game.Simulator.Continue()
+		return nil
+	case e.Join:
+		game.World.AddPlayer()
+		return nil
+	}
+	if e.Player < 0 || e.Player >= len(game.World.Players) {
+		return fmt.Errorf("game: replay has no player %d", e.Player)
+	}
+	var player = game.World.Players[e.Player]
+	if e.Leave {
+		game.dropPlayer(player)
+		return nil
+	}
+	input, err := e.input()
+	if err != nil {
+		return err
+	}
+	if input.Type == Restart {
+		return game.Restart()
+	}
+	game.Simulator.StepFor(player, input)
+	return nil
+}
+
+// Verify plays the replay through as fast as it goes and checks that it
+// ends in the recorded state.
+func (r *Replay) Verify(bestiary Bestiary) error {
+	game, err := r.NewGame(0, bestiary)
+	if err != nil {
+		return err
+	}
+	for _, e := range r.entries {
+		if err := game.apply(e); err != nil {
+			return err
+		}
+	}
+	return r.check(game)
+}
+
+func (r *Replay) check(game *Game) error {
+	if r.hash == "" {
+		return ErrReplayUnfinished
+	}
+	hash, err := game.StateHash()
+	if err != nil {
+		return err
+	}
+	if hash != r.hash {
+		return fmt.Errorf("%w: turn %d, hash %.12s, recorded %.12s", ErrReplayMismatch, game.Simulator.Turn, hash, r.hash)
+	}
+	return nil
+}
+
+// Play runs the replay on a game from NewGame in place of Run, for
+// watching: it sends its views the level after every input, waiting as
+// long between them as the player did divided by speed, not at all if
+// speed is 0. Once it's done it waits for QuitGame or for the last view
+// to close and then reports, like Verify, whether the game ended in the
+// recorded state, ErrReplayStopped if it was quit before. The views'
+// other inputs are ignored.
+func (r *Replay) Play(game *Game, speed float64) error {
+	defer close(game.done)
+	game.broadcast()
+	var start = time.Now()
+	for _, e := range r.entries {
+		var wait time.Duration
+		if speed > 0 {
+			wait = time.Duration(float64(e.Time)*float64(time.Millisecond)/speed) - time.Since(start)
+		}
+		if wait < 0 {
+			// behind, idle would take it for forever
+			wait = 0
+		}
+		if !game.idle(wait) {
+			return ErrReplayStopped
+		}
+		if err := game.apply(e); err != nil {
+			return err
+		}
+		game.broadcast()
+	}
+	var err = r.check(game)
+	game.idle(-1)
+	return err
+}
+
+// idle handles the views' inputs for d, forever if d is negative. It
+// reports false once the game should end.
+func (game *Game) idle(d time.Duration) bool {
+	var timeout <-chan time.Time
+	if d >= 0 {
+		timeout = time.After(d)
+	}
+	for {
+		select {
+		case input, ok := <-game.InputChan:
+			if !ok {
+				return false
+			}
+			switch input.Type {
+			case QuitGame:
+				return false
+			case OpenWindow, Join:
+				// a replay has no seats, joining just watches
+				game.openWindow(input.LevelChannel)
+			case CloseWindow:
+				game.closeWindow(input.LevelChannel)
+				if len(game.LevelChans) == 0 {
+					return false
+				}
+			}
+		case <-timeout:
+			return true
+		}
+	}
+}
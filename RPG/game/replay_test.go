@@ -0,0 +1,155 @@
+package game
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// recordLog records inputs played through Run.
+func recordLog(t *testing.T, inputs ...*Input) string {
+	t.Helper()
+	var (
+		game = newTestGame(t)
+		buf  bytes.Buffer
+	)
+	if err := game.Record(&buf); err != nil {
+		t.Fatal(err)
+	}
+	runGame(t, game, inputs...)
+	return buf.String()
+}
+
+// recordGame is recordLog read back.
+func recordGame(t *testing.T, inputs ...*Input) *Replay {
+	t.Helper()
+	replay, err := ReadReplay(strings.NewReader(recordLog(t, inputs...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return replay
+}
+
+// solo are the inputs of a short game of one player.
+func solo() []*Input {
+	var view = NewLevelChan()
+	var inputs = []*Input{{Type: Join, LevelChannel: view}}
+	for _, t := range []InputType{Right, Right, Down, None, Pickup, Left, Up, Right} {
+		inputs = append(inputs, &Input{Type: t, LevelChannel: view})
+	}
+	return append(inputs, &Input{Type: Travel, Target: Position{5, 2}, LevelChannel: view})
+}
+
+func TestRecordVerify(t *testing.T) {
+	var replay = recordGame(t, solo()...)
+	// the first to join plays World.Player, that isn't an entry
+	if replay.hash == "" || len(replay.entries) != len(solo())-1 {
+		t.Fatalf("recorded %d entries, hash %q", len(replay.entries), replay.hash)
+	}
+	if err := replay.Verify(stockBestiary(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTamperedReplay(t *testing.T) {
+	var (
+		log   = recordLog(t, solo()...)
+		seed  = regexp.MustCompile(`"seed":\d+`)
+		hash  = regexp.MustCompile(`"hash":"[0-9a-f]`)
+		tests = []struct {
+			name   string
+			tamper func(string) string
+			err    error  // what Verify says, nil if ReadReplay fails
+			msg    string // or that if there's no error value
+		}{
+			{"input", func(log string) string { return strings.Replace(log, `"input":"right"`, `"input":"wait"`, 1) }, ErrReplayMismatch, ""},
+			{"seed", func(log string) string { return seed.ReplaceAllString(log, `"seed":12345`) }, ErrReplayMismatch, ""},
+			{"hash", func(log string) string { return hash.ReplaceAllString(log, `"hash":"x`) }, ErrReplayMismatch, ""},
+			{"cut short", func(log string) string { return log[:strings.LastIndex(strings.TrimSuffix(log, "\n"), "\n")+1] }, ErrReplayUnfinished, ""},
+			{"map", func(log string) string { return strings.Replace(log, `"sha256":"`, `"sha256":"0`, 1) }, nil, "game: maps/entrance.map has changed since the replay was recorded"},
+			{"bestiary", func(log string) string { return strings.Replace(log, `"bestiary":"`, `"bestiary":"0`, 1) }, nil, "game: the bestiary has changed since the replay was recorded"},
+			{"player", func(log string) string { return strings.Replace(log, `"input":"down"`, `"player":3,"input":"down"`, 1) }, nil, "game: replay has no player 3"},
+			{"unknown input", func(log string) string { return strings.Replace(log, `"input":"down"`, `"input":"jump"`, 1) }, nil, ""},
+		}
+	)
+	if err := mustReadReplay(t, log).Verify(stockBestiary(t)); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tampered = test.tamper(log)
+			if tampered == log {
+				t.Fatal("nothing to tamper with")
+			}
+			replay, err := ReadReplay(strings.NewReader(tampered))
+			if test.err == nil && test.msg == "" {
+				if err == nil {
+					t.Error("read a replay with an unknown input")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = replay.Verify(stockBestiary(t))
+			if test.err != nil && !errors.Is(err, test.err) || test.msg != "" && (err == nil || err.Error() != test.msg) {
+				t.Errorf("verified with %v, want %v%s", err, test.err, test.msg)
+			}
+		})
+	}
+}
+
+func TestReplayBestiary(t *testing.T) {
+	var (
+		replay   = mustReadReplay(t, recordLog(t, solo()...))
+		bestiary = make(Bestiary)
+	)
+	for name, def := range stockBestiary(t) {
+		var d = *def
+		bestiary[name] = &d
+	}
+	// sprites are only looks
+	bestiary["Rat"].Sprite.X++
+	if err := replay.Verify(bestiary); err != nil {
+		t.Errorf("a new rat sprite: %v", err)
+	}
+	bestiary["Rat"].Hitpoints++
+	if err := replay.Verify(bestiary); err == nil {
+		t.Error("verified with a tougher rat")
+	}
+}
+
+func TestOldReplay(t *testing.T) {
+	var log = strings.Replace(recordLog(t, solo()...), fmt.Sprintf(`"version":%d`, ReplayVersion), `"version":2`, 1)
+	if _, err := ReadReplay(strings.NewReader(log)); err != ErrOldReplay {
+		t.Errorf("read a version 2 replay: %v", err)
+	}
+}
+
+func mustReadReplay(t *testing.T, log string) *Replay {
+	t.Helper()
+	replay, err := ReadReplay(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return replay
+}
+
+func TestRecordParty(t *testing.T) {
+	var host, guest = NewLevelChan(), NewLevelChan()
+	var replay = recordGame(t,
+		&Input{Type: Join, LevelChannel: host},
+		&Input{Type: Join, LevelChannel: guest},
+		&Input{Type: Right, LevelChannel: guest},
+		&Input{Type: Down, LevelChannel: host},
+		&Input{Type: Left, LevelChannel: guest},
+		&Input{Type: CloseWindow, LevelChannel: guest},
+		&Input{Type: Right, LevelChannel: host},
+	)
+	if err := replay.Verify(stockBestiary(t)); err != nil {
+		t.Fatal(err)
+	}
+}
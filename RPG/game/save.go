@@ -0,0 +1,312 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SaveVersion is written into every save. Load accepts older versions and
+// ignores fields it doesn't know, so saves stay loadable as the game grows;
+// version 1 had no party, diagonal moves or notices, which load as none.
+const SaveVersion = 2
+
+type saveFile struct {
+	Version  int    `json:"version"`
+	Turn     int    `json:"turn"`
+	GameOver bool   `json:"game_over"`
+	Current  int    `json:"current"`
+	Player   Player `json:"player"`
+	// Party is everyone who joined Player, see World.AddPlayer
+	Party    []Player    `json:"party,omitempty"`
+	Combat   *saveCombat `json:"combat,omitempty"`
+	Diagonal bool        `json:"diagonal,omitempty"`
+	Levels   []saveLevel `json:"levels"`
+}
+
+// saveCombat keeps the rules and, if it is a SeededRNG, where the
+// generator is so that a loaded game rolls the same dice.
+type saveCombat struct {
+	Rules CombatRules `json:"rules"`
+	RNG   *uint64     `json:"rng,omitempty"`
+}
+
+type saveLevel struct {
+	Name     string        `json:"name"`
+	Map      []string      `json:"map"`
+	Monsters []Monster     `json:"monsters"`
+	Items    []Item        `json:"items,omitempty"`
+	Triggers []saveTrigger `json:"triggers,omitempty"`
+	Start    *Position     `json:"start,omitempty"`
+	Explored []Position    `json:"explored,omitempty"`
+	Turn     int           `json:"turn"`
+	Events   []saveEvent   `json:"events"`
+}
+
+type saveTrigger struct {
+	Position
+	Name string `json:"name"`
+}
+
+type saveEvent struct {
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+// decodeEvent returns a nil Event for types written by a newer version.
+func decodeEvent(typ string, raw json.RawMessage) (Event, error) {
+	switch typ {
+	case "move":
+		var e MoveEvent
+		return e, json.Unmarshal(raw, &e)
+	case "attack":
+		var e AttackEvent
+		return e, json.Unmarshal(raw, &e)
+	case "damage":
+		var e DamageEvent
+		return e, json.Unmarshal(raw, &e)
+	case "death":
+		var e DeathEvent
+		return e, json.Unmarshal(raw, &e)
+	case "door":
+		var e DoorOpenedEvent
+		return e, json.Unmarshal(raw, &e)
+	case "stairs":
+		var e StairsEvent
+		return e, json.Unmarshal(raw, &e)
+	case "item":
+		var e ItemEvent
+		return e, json.Unmarshal(raw, &e)
+	case "experience":
+		var e ExperienceEvent
+		return e, json.Unmarshal(raw, &e)
+	case "levelup":
+		var e LevelUpEvent
+		return e, json.Unmarshal(raw, &e)
+	case "notice":
+		var e NoticeEvent
+		return e, json.Unmarshal(raw, &e)
+	default:
+		return nil, nil
+	}
+}
+
+func eventType(e Event) string {
+	switch e.(type) {
+	case MoveEvent:
+		return "move"
+	case AttackEvent:
+		return "attack"
+	case DamageEvent:
+		return "damage"
+	case DeathEvent:
+		return "death"
+	case DoorOpenedEvent:
+		return "door"
+	case StairsEvent:
+		return "stairs"
+	case ItemEvent:
+		return "item"
+	case ExperienceEvent:
+		return "experience"
+	case LevelUpEvent:
+		return "levelup"
+	case NoticeEvent:
+		return "notice"
+	default:
+		return ""
+	}
+}
+
+// Save writes the whole world, the player and the turn counters to w.
+func (game *Game) Save(w io.Writer) error {
+	file, err := game.saveFile()
+	if err != nil {
+		return err
+	}
+	file.Version = SaveVersion
+	var encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(file)
+}
+
+// saveFile is what Save writes but the version.
+func (game *Game) saveFile() (saveFile, error) {
+	var (
+		s    = game.Simulator
+		file = saveFile{
+			Turn:     s.Turn,
+			GameOver: s.GameOver,
+			Current:  s.World.Current,
+			Player:   *s.World.Player,
+			Diagonal: s.World.Diagonal,
+		}
+	)
+	if combat := s.World.Combat; combat != nil {
+		file.Combat = &saveCombat{Rules: combat.Rules}
+		if rng, ok := combat.RNG.(*SeededRNG); ok {
+			var state = rng.State
+			file.Combat.RNG = &state
+		}
+	}
+	for _, p := range s.World.Players[1:] {
+		file.Party = append(file.Party, *p)
+	}
+	for _, level := range s.World.Levels {
+		saved, err := saveLevelState(level)
+		if err != nil {
+			return file, err
+		}
+		file.Levels = append(file.Levels, saved)
+	}
+	return file, nil
+}
+
+func saveLevelState(level *Level) (saveLevel, error) {
+	var saved = saveLevel{Name: level.Name, Turn: level.turn}
+	for _, row := range level.Map {
+		var line = make([]rune, len(row))
+		for x, tile := range row {
+			if tile == Blank {
+				line[x] = ' '
+			} else {
+				line[x] = rune(tile)
+			}
+		}
+		saved.Map = append(saved.Map, string(line))
+	}
+	for _, monster := range level.sortedMonsters() {
+		saved.Monsters = append(saved.Monsters, *monster)
+	}
+	for y, row := range level.Map {
+		for x := range row {
+			for _, item := range level.Items[Position{x, y}] {
+				saved.Items = append(saved.Items, *item)
+			}
+			if level.Explored[Position{x, y}] {
+				saved.Explored = append(saved.Explored, Position{x, y})
+			}
+		}
+	}
+	for pos, name := range level.Triggers {
+		saved.Triggers = append(saved.Triggers, saveTrigger{pos, name})
+	}
+	sort.Slice(saved.Triggers, func(i, j int) bool {
+		a, b := saved.Triggers[i].Position, saved.Triggers[j].Position
+		return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+	})
+	if level.hasStart {
+		var start = level.start
+		saved.Start = &start
+	}
+	for _, e := range level.Events.All() {
+		var typ = eventType(e)
+		if typ == "" {
+			continue
+		}
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return saved, err
+		}
+		saved.Events = append(saved.Events, saveEvent{typ, raw})
+	}
+	return saved, nil
+}
+
+// Load replaces the game state with a save written by Save. Call it
+// before Run or from the game goroutine; windows keep their channels.
+func (game *Game) Load(r io.Reader) error {
+	var file saveFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("game: reading save: %w", err)
+	}
+	if file.Version < 1 {
+		return fmt.Errorf("game: unsupported save version %d", file.Version)
+	}
+	if len(file.Levels) == 0 || file.Current < 0 || file.Current >= len(file.Levels) {
+		return fmt.Errorf("game: save has no level %d", file.Current)
+	}
+
+	var player = &file.Player
+	if player.Level == 0 {
+		// saved before experience
+		player.Level = DefaultProgression.LevelFor(player.Experience)
+	}
+	var levels = make([]*Level, len(file.Levels))
+	for i, saved := range file.Levels {
+		level, err := loadLevelState(saved, player)
+		if err != nil {
+			return fmt.Errorf("game: level %d: %w", i, err)
+		}
+		levels[i] = level
+	}
+	var party = []*Player{player}
+	for i := range file.Party {
+		party = append(party, &file.Party[i])
+	}
+	game.World = &World{Levels: levels, Current: file.Current}
+	game.World.setParty(party)
+	var combat = NewResolver(DefaultRules, NewRNG(1))
+	if file.Combat != nil {
+		combat.Rules = file.Combat.Rules
+		if file.Combat.RNG != nil {
+			combat.RNG = &SeededRNG{*file.Combat.RNG}
+		}
+	}
+	game.World.SetCombat(combat)
+	game.World.SetDiagonal(file.Diagonal)
+	game.World.Level().updateVision()
+	game.Simulator = &Simulator{World: game.World, Turn: file.Turn, GameOver: file.GameOver}
+	game.reseat()
+	return nil
+}
+
+func loadLevelState(saved saveLevel, player *Player) (*Level, error) {
+	var width = 0
+	for _, row := range saved.Map {
+		if n := len([]rune(row)); n > width {
+			width = n
+		}
+	}
+	var level = NewLevel(saved.Name, width, len(saved.Map))
+	level.Player = player
+	level.turn = saved.Turn
+	for y, row := range saved.Map {
+		for x, c := range []rune(row) {
+			if c != ' ' {
+				level.Map[y][x] = Title(c)
+			}
+		}
+	}
+	for i := range saved.Monsters {
+		var m = &saved.Monsters[i]
+		if _, taken := level.Monsters[m.Position]; taken {
+			return nil, fmt.Errorf("two monsters at %v", m.Position)
+		}
+		level.Monsters[m.Position] = m
+	}
+	for i := range saved.Items {
+		level.addItem(&saved.Items[i])
+	}
+	for _, pos := range saved.Explored {
+		level.Explored[pos] = true
+	}
+	for _, t := range saved.Triggers {
+		level.Triggers[t.Position] = t.Name
+	}
+	if saved.Start != nil {
+		level.start = *saved.Start
+		level.hasStart = true
+	}
+	for _, e := range saved.Events {
+		event, err := decodeEvent(e.Type, e.Event)
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			level.Events.Publish(event)
+		}
+	}
+	return level, nil
+}
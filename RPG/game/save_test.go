@@ -0,0 +1,93 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	var game = newTestGame(t)
+	game.World.AddPlayer()
+	game.World.SetDiagonal(true)
+	for _, input := range []InputType{Right, DownRight, None, Pickup} {
+		game.Simulator.Step(Input{Type: input})
+	}
+	var buf bytes.Buffer
+	if err := game.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var saved = buf.String()
+
+	var loaded = newTestGame(t)
+	if err := loaded.Load(strings.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := game.StateHash()
+	got, _ := loaded.StateHash()
+	if got != want || !loaded.World.Diagonal || len(loaded.World.Players) != 2 {
+		t.Error("the loaded game isn't the saved one")
+	}
+
+	if err := newTestGame(t).Load(strings.NewReader(strings.Replace(saved, `"version": 2`, `"version": 0`, 1))); err == nil || err.Error() != "game: unsupported save version 0" {
+		t.Errorf("version 0: %v", err)
+	}
+}
+
+// TestLoadOtherVersions loads a save the way version 1 wrote it, before
+// experience and loot tables, and one from a newer version with fields
+// this one doesn't know.
+func TestLoadOtherVersions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestGame(t).Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		player  = file["player"].(map[string]interface{})
+		level   = file["levels"].([]interface{})[0].(map[string]interface{})
+		monster = level["monsters"].([]interface{})[0].(map[string]interface{})
+		encode  = func() string {
+			data, err := json.Marshal(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+	)
+
+	file["version"] = 1
+	player["Experience"] = DefaultProgression.Threshold(2)
+	delete(player, "Level")
+	monster["Loot"] = []string{"Sword"}
+	var old = newTestGame(t)
+	if err := old.Load(strings.NewReader(encode())); err != nil {
+		t.Fatal(err)
+	}
+	if old.World.Player.Level != 2 {
+		t.Errorf("version 1 player is level %d, want 2", old.World.Player.Level)
+	}
+	var dropped = false
+	for _, m := range old.World.Level().Monsters {
+		dropped = dropped || len(m.Loot) == 1 && m.Loot[0] == LootDrop{"Sword", 100}
+	}
+	if !dropped {
+		t.Error("version 1 loot didn't load")
+	}
+
+	file["version"] = SaveVersion + 1
+	file["weather"] = "rain"
+	player["Mana"] = 10
+	level["events"] = []interface{}{map[string]interface{}{"type": "quake", "event": map[string]int{"strength": 9}}}
+	var newer = newTestGame(t)
+	if err := newer.Load(strings.NewReader(encode())); err != nil {
+		t.Fatalf("newer save: %v", err)
+	}
+	if n := newer.World.Level().Events.Len(); n != 0 {
+		t.Errorf("%d events of an unknown type loaded", n)
+	}
+}
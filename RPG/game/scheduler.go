@@ -0,0 +1,74 @@
+package game
+
+import "math"
+
+// The player and the monsters share one timeline. Every tick each
+// character earns Speed*Tick energy (kept in ActionPoints); whoever has
+// ActionThreshold or more may act and pays the action's cost. A character
+// with Speed 1 thus gets one full-cost action per turn, a Rat with Speed
+// 1.5 three actions every two turns.
+
+// ActionCosts is what each kind of action takes off ActionPoints.
+// Counter-attacks are reactions and cost nothing.
+type ActionCosts struct {
+	Move     float64
+	Attack   float64
+	OpenDoor float64
+	Wait     float64
+	Item     float64
+}
+
+var Costs = ActionCosts{
+	Move:     1,
+	Attack:   1,
+	OpenDoor: 0.5,
+	Wait:     1,
+	Item:     1,
+}
+
+const (
+	ActionThreshold = 1.0
+	// Tick is the game time between two chances to act. It's a power of
+	// two so that whole speeds add up without rounding errors.
+	Tick = 0.25
+)
+
+func (c *Character) ready() bool {
+	return c.Hitpoints > 0 && c.ActionPoints >= ActionThreshold
+}
+
+// advance runs the timeline until p can act. Within a tick the monsters
+// act in reading order, each as often as its energy allows. The rest of
+// the party earns energy alongside but never more than for one action,
+// they only act when their own inputs come in.
+func (level *Level) advance(p *Player) {
+	if p.Speed <= 0 {
+		// would wait forever
+		p.ActionPoints = ActionThreshold
+	}
+	level.runMonsters()
+	for !p.ready() && p.Hitpoints > 0 {
+		p.ActionPoints += p.Speed * Tick
+		for _, other := range level.Players {
+			if other != p && other.ActionPoints < ActionThreshold {
+				other.ActionPoints = math.Min(other.ActionPoints+other.Speed*Tick, ActionThreshold)
+			}
+		}
+		for _, m := range level.sortedMonsters() {
+			m.ActionPoints += m.Speed * Tick
+		}
+		level.runMonsters()
+	}
+}
+
+func (level *Level) runMonsters() {
+	for _, m := range level.sortedMonsters() {
+		for m.ready() && level.partyAlive() {
+			var cost = m.Act(level)
+			if cost <= 0 {
+				cost = Costs.Wait
+			}
+			m.ActionPoints -= cost
+		}
+	}
+}
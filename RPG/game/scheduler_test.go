@@ -0,0 +1,87 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+// waitAI only waits, and notes down who it waited for.
+type waitAI struct{ acts *[]string }
+
+func (ai waitAI) Act(m *Monster, level *Level) Action {
+	*ai.acts = append(*ai.acts, m.Name)
+	return Action{Kind: ActWait}
+}
+
+// TestAdvance runs the timeline for a few of the player's turns with
+// monsters that only wait, and checks who got to act when. @ is the
+// player.
+func TestAdvance(t *testing.T) {
+	const rows = "########\n#@..S.S#\n#.S....#\n########"
+	var tests = []struct {
+		name   string
+		player float64
+		speeds map[string]float64
+		turns  int
+		acts   []string
+	}{
+		{"same speed", 1, map[string]float64{"4 1": 1, "6 1": 1}, 2,
+			[]string{"4 1", "6 1", "@", "4 1", "6 1", "@"}},
+		{"reading order", 1, map[string]float64{"6 1": 1, "2 2": 1}, 1,
+			[]string{"6 1", "2 2", "@"}},
+		{"twice as fast", 1, map[string]float64{"4 1": 1, "6 1": 2}, 2,
+			[]string{"6 1", "4 1", "6 1", "@", "6 1", "4 1", "6 1", "@"}},
+		{"half as fast", 1, map[string]float64{"4 1": 0.5}, 4,
+			[]string{"@", "4 1", "@", "@", "4 1", "@"}},
+		{"half again as fast", 1, map[string]float64{"4 1": 1.5}, 2,
+			[]string{"4 1", "@", "4 1", "4 1", "@"}},
+		// a monster ready in the same tick as the player goes first
+		{"faster player", 2, map[string]float64{"4 1": 1}, 3,
+			[]string{"@", "4 1", "@", "@"}},
+		{"standing still", 1, map[string]float64{"4 1": 0}, 2,
+			[]string{"@", "@"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var acts []string
+			AIProfiles["wait"] = waitAI{&acts}
+			defer delete(AIProfiles, "wait")
+
+			var level = testWorld(t, rows).Level()
+			level.Player.Speed = test.player
+			for _, m := range level.Monsters {
+				m.AI = "wait"
+				m.Speed = test.speeds[m.Name]
+			}
+			for i := 0; i < test.turns; i++ {
+				level.advance(level.Player)
+				acts = append(acts, "@")
+				level.Player.ActionPoints -= Costs.Wait
+			}
+			if fmt.Sprint(acts) != fmt.Sprint(test.acts) {
+				t.Errorf("acts %q, want %q", acts, test.acts)
+			}
+		})
+	}
+}
+
+func TestAdvanceParty(t *testing.T) {
+	var (
+		world  = testWorld(t, "#####", "#@..#", "#####")
+		level  = world.Level()
+		second = world.AddPlayer()
+	)
+	// whoever waits for their input doesn't bank more than one action
+	second.Speed = 3
+	level.advance(level.Player)
+	if level.Player.ActionPoints != ActionThreshold || second.ActionPoints != ActionThreshold {
+		t.Errorf("energy %v and %v, want %v each", level.Player.ActionPoints, second.ActionPoints, ActionThreshold)
+	}
+
+	// a player who can't earn energy acts anyway rather than wait forever
+	level.Player.ActionPoints, level.Player.Speed = 0, 0
+	level.advance(level.Player)
+	if level.Player.ActionPoints != ActionThreshold {
+		t.Errorf("energy %v at speed 0, want %v", level.Player.ActionPoints, ActionThreshold)
+	}
+}
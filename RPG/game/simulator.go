@@ -0,0 +1,234 @@
+package game
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrGameOver     = errors.New("game: game is over")
+	ErrInvalidInput = errors.New("game: input is not a turn action")
+	ErrPlayerDead   = errors.New("game: player is dead")
+)
+
+type MoveResult struct {
+	Name     string
+	From, To Position
+}
+
+type AttackResult struct {
+	Attacker string
+	Defender string
+	Position Position
+	Hit      bool
+	Critical bool
+	Damage   int
+}
+
+type DeathResult struct {
+	Name     string
+	Position Position
+}
+
+// StepResult is everything that happened during a single turn.
+// Level is the index of the level the player ended the turn on.
+type StepResult struct {
+	Turn     int
+	Level    int
+	Moves    []MoveResult
+	Attacks  []AttackResult
+	Deaths   []DeathResult
+	GameOver bool
+}
+
+// Simulator advances the current level of a World one turn at a time.
+// It never touches channels or the renderer, so it can be driven from
+// tests and bots. A turn is one input of one player, the game is over
+// once the whole party is dead.
+type Simulator struct {
+	World    *World
+	Turn     int
+	GameOver bool
+
+	travels map[*Player]*travel
+}
+
+func NewSimulator(world *World) *Simulator {
+	return &Simulator{World: world}
+}
+
+func isTurnAction(t InputType) bool {
+	switch t {
+	case None, Up, Down, Left, Right, UpLeft, UpRight, DownLeft, DownRight, Pickup, Drop, Use:
+		return true
+	default:
+		return false
+	}
+}
+
+// Step applies one input: the timeline runs until the player may act,
+// with the monsters acting as their energy allows, then the player acts.
+// None is a valid input and makes the player wait. Travel and Explore
+// plan a walk and take its first step, Continue takes the rest; any other
+// input cancels the walk.
+func (s *Simulator) Step(input Input) (StepResult, error) {
+	return s.StepFor(s.World.Player, input)
+}
+
+// StepFor is Step for one player of the party.
+func (s *Simulator) StepFor(player *Player, input Input) (StepResult, error) {
+	delete(s.travels, player)
+	if s.GameOver {
+		return StepResult{Turn: s.Turn, Level: s.World.Current, GameOver: true}, ErrGameOver
+	}
+	if player.Hitpoints <= 0 {
+		return StepResult{Turn: s.Turn, Level: s.World.Current}, ErrPlayerDead
+	}
+	switch input.Type {
+	case Travel, Explore:
+		if err := s.plan(player, input); err != nil {
+			return StepResult{Turn: s.Turn, Level: s.World.Current}, err
+		}
+		return s.continueTravel(player)
+	}
+	if !isTurnAction(input.Type) {
+		return StepResult{Turn: s.Turn, Level: s.World.Current}, ErrInvalidInput
+	}
+	return s.turn(player, input)
+}
+
+func (s *Simulator) turn(player *Player, input Input) (StepResult, error) {
+	s.Turn++
+	var (
+		level  = s.World.Level()
+		result = StepResult{Turn: s.Turn}
+	)
+	level.turn = s.Turn
+	defer level.Events.Subscribe(result.collect)()
+
+	level.advance(player)
+	if player.Hitpoints > 0 {
+		player.ActionPoints -= s.handleInput(player, input)
+	}
+	s.World.Level().updateVision()
+	if !s.World.Level().partyAlive() {
+		s.GameOver = true
+		result.GameOver = true
+	}
+	result.Level = s.World.Current
+	return result, nil
+}
+
+func (result *StepResult) collect(e Event) {
+	switch e := e.(type) {
+	case MoveEvent:
+		result.Moves = append(result.Moves, MoveResult{e.Actor.Name, e.From, e.Position})
+	case AttackEvent:
+		result.Attacks = append(result.Attacks, AttackResult{e.Actor.Name, e.Target.Name, e.Position, e.Hit, e.Critical, 0})
+	case DamageEvent:
+		// follows the AttackEvent of the same blow
+		if n := len(result.Attacks); n > 0 {
+			result.Attacks[n-1].Damage = e.Amount
+		}
+	case DeathEvent:
+		result.Deaths = append(result.Deaths, DeathResult{e.Target.Name, e.Position})
+	}
+}
+
+// handleInput carries out the player's action and returns its cost.
+// Bumping into a wall or another player is not an action and costs
+// nothing, and neither is an item input that does nothing.
+func (s *Simulator) handleInput(player *Player, input Input) float64 {
+	var (
+		level  = s.World.Level()
+		newPos = player.Position
+	)
+	switch input.Type {
+	case Up:
+		newPos.Y--
+	case Down:
+		newPos.Y++
+	case Left:
+		newPos.X--
+	case Right:
+		newPos.X++
+	case UpLeft, UpRight, DownLeft, DownRight:
+		newPos = diagonalStep(newPos, input.Type)
+		// no squeezing between two walls
+		if !level.diagonal || !canPass(level, Position{newPos.X, player.Y}) || !canPass(level, Position{player.X, newPos.Y}) {
+			return 0
+		}
+	case Pickup, Drop, Use:
+		if !s.handleItem(player, input) {
+			return 0
+		}
+		return Costs.Item
+	default:
+		return Costs.Wait
+	}
+	if canWalk(level, newPos) {
+		if _, fight := level.Monsters[newPos]; fight {
+			player.Move(newPos, level)
+			return Costs.Attack
+		}
+		if level.playerAt(newPos) != nil {
+			return 0
+		}
+		var cost = Costs.Move * terrainCost(level, newPos)
+		player.Move(newPos, level)
+		if player.Position == newPos && s.World.takeStairs(player) {
+			// the party left, and with it the walks planned there
+			s.travels = nil
+		}
+		return cost
+	}
+	if openDoor(level, newPos, &player.Character) {
+		return Costs.OpenDoor
+	}
+	return 0
+}
+
+// handleItem reports whether the item input did anything: nothing to
+// pick up, a full inventory or a potion at full health cost nothing.
+func (s *Simulator) handleItem(player *Player, input Input) bool {
+	var level = s.World.Level()
+	switch input.Type {
+	case Pickup:
+		return player.pickup(level)
+	case Drop:
+		return player.drop(input.Item, level)
+	default:
+		return player.use(input.Item, level)
+	}
+}
+
+func diagonalStep(pos Position, dir InputType) Position {
+	switch dir {
+	case UpLeft:
+		return Position{pos.X - 1, pos.Y - 1}
+	case UpRight:
+		return Position{pos.X + 1, pos.Y - 1}
+	case DownLeft:
+		return Position{pos.X - 1, pos.Y + 1}
+	case DownRight:
+		return Position{pos.X + 1, pos.Y + 1}
+	}
+	return pos
+}
+
+// sortedMonsters returns the monsters in reading order (top to bottom,
+// left to right) so that a turn doesn't depend on map iteration order.
+func (level *Level) sortedMonsters() []*Monster {
+	var monsters = make([]*Monster, 0, len(level.Monsters))
+	for _, m := range level.Monsters {
+		monsters = append(monsters, m)
+	}
+	sort.Slice(monsters, func(i, j int) bool {
+		a, b := monsters[i].Position, monsters[j].Position
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.X < b.X
+	})
+	return monsters
+}
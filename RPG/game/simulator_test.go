@@ -0,0 +1,178 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testWorld is a world of one version 0 level drawn from rows. Its
+// monsters are named after where they stand.
+func testWorld(t *testing.T, rows ...string) *World {
+	t.Helper()
+	mf, err := ParseMap(strings.NewReader(strings.Join(rows, "\n")), stockBestiary(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	level, err := mf.Level()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pos, m := range level.Monsters {
+		m.Name = fmt.Sprint(pos.X, pos.Y)
+	}
+	world, err := NewWorld(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return world
+}
+
+func TestStepEnergy(t *testing.T) {
+	var tests = []struct {
+		name     string
+		rows     []string
+		diagonal bool
+		inputs   []InputType
+		turn     int
+		energy   float64
+		pos      Position
+	}{
+		{"wait", []string{"#####", "#.@.#", "#####"}, false, []InputType{None}, 1, 0, Position{2, 1}},
+		{"move", []string{"#####", "#.@.#", "#####"}, false, []InputType{Right}, 1, 0, Position{3, 1}},
+		{"bump", []string{"#####", "#.@.#", "#####"}, false, []InputType{Up}, 1, ActionThreshold, Position{2, 1}},
+		{"open door", []string{"#####", "#@|.#", "#####"}, false, []InputType{Right}, 1, ActionThreshold - Costs.OpenDoor, Position{1, 1}},
+		{"through door", []string{"#####", "#@|.#", "#####"}, false, []InputType{Right, Right, Right}, 3, 0, Position{3, 1}},
+		{"mud", []string{"#####", "#@,.#", "#####"}, false, []InputType{Right, Right}, 2, 0, Position{3, 1}},
+		{"no diagonals", []string{"#####", "#@..#", "#...#", "#####"}, false, []InputType{DownRight}, 1, ActionThreshold, Position{1, 1}},
+		{"diagonal", []string{"#####", "#@..#", "#...#", "#####"}, true, []InputType{DownRight}, 1, 0, Position{2, 2}},
+		{"corner", []string{"#####", "#@#.#", "#...#", "#####"}, true, []InputType{DownRight}, 1, ActionThreshold, Position{1, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s = NewSimulator(testWorld(t, test.rows...))
+			s.World.SetDiagonal(test.diagonal)
+			for _, input := range test.inputs {
+				if _, err := s.Step(Input{Type: input}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var player = s.World.Player
+			if s.Turn != test.turn || player.ActionPoints != test.energy || player.Position != test.pos {
+				t.Errorf("turn %d, energy %v at %v, want turn %d, energy %v at %v",
+					s.Turn, player.ActionPoints, player.Position, test.turn, test.energy, test.pos)
+			}
+		})
+	}
+}
+
+// TestTurnOrder has the player wait next to monsters and counts their
+// blows: a Rat is half again as fast as a Spider, and monsters ready in
+// the same tick act in reading order.
+func TestTurnOrder(t *testing.T) {
+	var tests = []struct {
+		name  string
+		rows  []string
+		turns int
+		blows []string
+	}{
+		{"spider", []string{"#####", "#@S.#", "#####"}, 2, []string{"2 1", "2 1"}},
+		{"rat", []string{"#####", "#@R.#", "#####"}, 2, []string{"2 1", "2 1", "2 1"}},
+		{"reading order", []string{"#####", "#.S.#", "#S@.#", "#####"}, 1, []string{"2 1", "1 2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				world = testWorld(t, test.rows...)
+				s     = NewSimulator(world)
+				blows []string
+			)
+			world.Player.Hitpoints = 1000
+			for i := 0; i < test.turns; i++ {
+				result, err := s.Step(Input{Type: None})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, a := range result.Attacks {
+					if a.Attacker != world.Player.Name {
+						// not a counter-attack
+						blows = append(blows, a.Attacker)
+					}
+				}
+			}
+			if fmt.Sprint(blows) != fmt.Sprint(test.blows) {
+				t.Errorf("blows %q, want %q", blows, test.blows)
+			}
+		})
+	}
+}
+
+func TestStepFor(t *testing.T) {
+	var (
+		world  = testWorld(t, "######", "#@...#", "######")
+		s      = NewSimulator(world)
+		second = world.AddPlayer()
+		from   = second.Position
+	)
+	if _, err := s.StepFor(second, Input{Type: Right}); err != nil {
+		t.Fatal(err)
+	}
+	if second.Position != (Position{from.X + 1, from.Y}) || second.ActionPoints != 0 {
+		t.Errorf("second at %v with energy %v, want %v with 0", second.Position, second.ActionPoints, Position{from.X + 1, from.Y})
+	}
+	// the first earned energy alongside, but only for one action
+	if world.Player.ActionPoints != ActionThreshold || world.Player.Position != (Position{1, 1}) {
+		t.Errorf("first at %v with energy %v, want 1,1 with %v", world.Player.Position, world.Player.ActionPoints, ActionThreshold)
+	}
+
+	second.Hitpoints = 0
+	if _, err := s.StepFor(second, Input{Type: Right}); err != ErrPlayerDead {
+		t.Errorf("dead player stepped, err %v", err)
+	}
+	if _, err := s.StepFor(world.Player, Input{Type: QuitGame}); err != ErrInvalidInput {
+		t.Errorf("QuitGame stepped, err %v", err)
+	}
+}
+
+func TestContinue(t *testing.T) {
+	var (
+		world = testWorld(t, "#######", "#@....#", "#######")
+		s     = NewSimulator(world)
+	)
+	if _, err := s.Continue(); err != ErrNotTraveling {
+		t.Fatalf("Continue before traveling: %v", err)
+	}
+	if _, err := s.Step(Input{Type: Travel, Target: Position{5, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	var steps = 1
+	for s.Traveling() {
+		if _, err := s.Continue(); err != nil {
+			t.Fatal(err)
+		}
+		steps++
+	}
+	if world.Player.Position != (Position{5, 1}) || steps != 4 || s.Turn != 4 {
+		t.Errorf("at %v after %d steps and %d turns, want 5,1 after 4", world.Player.Position, steps, s.Turn)
+	}
+
+	// the dead don't walk on
+	if _, err := s.Step(Input{Type: Travel, Target: Position{1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	world.Player.Hitpoints = 0
+	var turn = s.Turn
+	if _, err := s.Continue(); err != ErrPlayerDead || s.Traveling() || s.Turn != turn {
+		t.Errorf("dead player continued, err %v, traveling %v, turn %d", err, s.Traveling(), s.Turn)
+	}
+	world.Player.Hitpoints = world.Player.MaxHitpoints
+
+	// a wall is nowhere to travel to
+	if _, err := s.Step(Input{Type: Travel, Target: Position{0, 0}}); err != ErrNoPath {
+		t.Errorf("travel into the wall: %v", err)
+	}
+	var notice, ok = world.Level().Events.All()[world.Level().Events.Len()-1].(NoticeEvent)
+	if !ok || notice.Text != notices[ErrNoPath] {
+		t.Errorf("no notice for a travel that can't start")
+	}
+}
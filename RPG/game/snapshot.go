@@ -0,0 +1,327 @@
+package game
+
+import "sort"
+
+// Tile is what a snapshot knows about one square of the map.
+type Tile struct {
+	Title    Title
+	Explored bool
+	Visible  bool
+	Debug    bool
+}
+
+// Actor is a monster, or another player, in a snapshot. Its ID stays the
+// same from one snapshot to the next for as long as the monster lives or
+// the player stays; IDs start at 1.
+type Actor struct {
+	Character
+	ID int
+}
+
+// Snapshot is a level as it stood after a turn, what views get instead of
+// the *Level the game goroutine keeps changing. Nothing in a snapshot is
+// written once it's published, so any number of goroutines may read one
+// while the game carries on. Rows of tiles that didn't change are shared
+// with the snapshot before.
+type Snapshot struct {
+	// Version counts the snapshots the game has published
+	Version int
+	// Depth is the level's index in World.Levels
+	Depth    int
+	Name     string
+	Turn     int
+	GameOver bool
+	// Player is whom the view plays, a copy down to the inventory
+	Player Player
+	// Others are the rest of the party, the living
+	Others   map[Position]Actor
+	Monsters map[Position]Actor
+	Items    map[Position][]Item
+	// Events is the level's kept history, oldest first
+	Events []Event
+
+	tiles     [][]Tile
+	published int    // events ever published on the level
+	level     *Level // only compared, never read through
+}
+
+func (s *Snapshot) Width() int {
+	if len(s.tiles) == 0 {
+		return 0
+	}
+	return len(s.tiles[0])
+}
+
+func (s *Snapshot) Height() int {
+	return len(s.tiles)
+}
+
+func (s *Snapshot) InRange(pos Position) bool {
+	return pos.X >= 0 && pos.Y >= 0 && pos.X < s.Width() && pos.Y < s.Height()
+}
+
+// Tile is the tile at pos, a Blank one off the map.
+func (s *Snapshot) Tile(pos Position) Tile {
+	if !s.InRange(pos) {
+		return Tile{}
+	}
+	return s.tiles[pos.Y][pos.X]
+}
+
+// Actor finds a monster or another player by ID.
+func (s *Snapshot) Actor(id int) (Actor, bool) {
+	for _, actors := range []map[Position]Actor{s.Monsters, s.Others} {
+		for _, a := range actors {
+			if a.ID == id {
+				return a, true
+			}
+		}
+	}
+	return Actor{}, false
+}
+
+// Messages returns up to the last n formatted events that have text.
+func (s *Snapshot) Messages(n int) []string {
+	return messages(s.Events, n)
+}
+
+// PlayerDeath finds the event in which the player died, if they did.
+func (s *Snapshot) PlayerDeath() (DeathEvent, bool) {
+	return playerDeath(s.Events)
+}
+
+// Diff is what changed from one snapshot to another.
+type Diff struct {
+	// Full is set when there's nothing to compare with: no snapshot
+	// before, another level or a restart. Everything has to be redrawn.
+	Full bool
+	// Tiles are where the terrain, fog, items or who stands there
+	// changed, row by row
+	Tiles []Position
+	// Events were published in between, oldest first. Some are missing
+	// if the level's history has moved past them.
+	Events []Event
+}
+
+// Diff compares s with an earlier snapshot, prev may be nil.
+func (s *Snapshot) Diff(prev *Snapshot) Diff {
+	if prev == nil || prev.level != s.level || prev.Width() != s.Width() || prev.Height() != s.Height() {
+		return Diff{Full: true}
+	}
+	var changed = make(map[Position]bool)
+	for y, row := range s.tiles {
+		var old = prev.tiles[y]
+		if len(row) > 0 && &row[0] == &old[0] {
+			continue // shared, nothing in it changed
+		}
+		for x := range row {
+			if row[x] != old[x] {
+				changed[Position{x, y}] = true
+			}
+		}
+	}
+	if prev.Player.Position != s.Player.Position || prev.Player.Rune != s.Player.Rune {
+		changed[prev.Player.Position] = true
+		changed[s.Player.Position] = true
+	}
+	changedActors(changed, prev.Monsters, s.Monsters)
+	changedActors(changed, prev.Others, s.Others)
+	for pos, items := range s.Items {
+		if !sameItems(items, prev.Items[pos]) {
+			changed[pos] = true
+		}
+	}
+	for pos := range prev.Items {
+		if _, ok := s.Items[pos]; !ok {
+			changed[pos] = true
+		}
+	}
+
+	var diff = Diff{Tiles: make([]Position, 0, len(changed))}
+	for pos := range changed {
+		diff.Tiles = append(diff.Tiles, pos)
+	}
+	sort.Slice(diff.Tiles, func(i, j int) bool { return positionLess(diff.Tiles[i], diff.Tiles[j]) })
+	if n := s.published - prev.published; n > 0 {
+		if n > len(s.Events) {
+			n = len(s.Events)
+		}
+		diff.Events = s.Events[len(s.Events)-n:]
+	}
+	return diff
+}
+
+// changedActors marks where an actor came, went or was replaced.
+func changedActors(changed map[Position]bool, prev, actors map[Position]Actor) {
+	for pos, a := range actors {
+		if old, ok := prev[pos]; !ok || old.ID != a.ID || old.Rune != a.Rune {
+			changed[pos] = true
+		}
+	}
+	for pos := range prev {
+		if _, ok := actors[pos]; !ok {
+			changed[pos] = true
+		}
+	}
+}
+
+func sameItems(a, b []Item) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Rune != b[i].Rune {
+			return false
+		}
+	}
+	return true
+}
+
+func positionLess(a, b Position) bool {
+	return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+}
+
+func (level *Level) tile(x, y int) Tile {
+	var pos = Position{x, y}
+	return Tile{level.Map[y][x], level.Explored[pos], level.Visible[pos], level.Debug[pos]}
+}
+
+// snapshot copies the current level into a new version as World.Player
+// sees it, see viewFor for the others. Rows that match the last
+// snapshot's are reused rather than copied. Only the goroutine running
+// the game may call it.
+func (game *Game) snapshot() *Snapshot {
+	var (
+		level = game.Level()
+		prev  = game.last
+		s     = &Snapshot{
+			Version:   1,
+			Depth:     game.World.Current,
+			Name:      level.Name,
+			Turn:      game.Simulator.Turn,
+			GameOver:  game.Simulator.GameOver,
+			Player:    copyPlayer(level.Player),
+			Monsters:  make(map[Position]Actor, len(level.Monsters)),
+			Items:     make(map[Position][]Item, len(level.Items)),
+			Events:    level.Events.All(),
+			tiles:     make([][]Tile, len(level.Map)),
+			published: level.Events.published,
+			level:     level,
+		}
+	)
+	if prev != nil {
+		s.Version = prev.Version + 1
+		if prev.level != level {
+			prev = nil
+		}
+	}
+
+	for y, row := range level.Map {
+		if prev != nil && y < len(prev.tiles) && len(prev.tiles[y]) == len(row) {
+			s.tiles[y] = prev.tiles[y]
+			for x := range row {
+				if s.tiles[y][x] != level.tile(x, y) {
+					s.tiles[y] = nil
+					break
+				}
+			}
+		}
+		if s.tiles[y] == nil {
+			s.tiles[y] = make([]Tile, len(row))
+			for x := range row {
+				s.tiles[y][x] = level.tile(x, y)
+			}
+		}
+	}
+
+	// monsters keep their IDs, new ones are numbered top to bottom so
+	// that replaying a game numbers them the same; players get theirs
+	// once there's more than one
+	var (
+		ids        = make(map[*Character]int, len(level.Monsters))
+		positions  = make([]Position, 0, len(level.Monsters))
+		characters []*Character
+	)
+	for pos := range level.Monsters {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positionLess(positions[i], positions[j]) })
+	for _, pos := range positions {
+		characters = append(characters, &level.Monsters[pos].Character)
+	}
+	if len(level.Players) > 1 {
+		for _, p := range level.Players {
+			characters = append(characters, &p.Character)
+		}
+	}
+	for _, c := range characters {
+		id, ok := game.ids[c]
+		if !ok {
+			game.nextID++
+			id = game.nextID
+		}
+		ids[c] = id
+	}
+	game.ids = ids
+	for _, pos := range positions {
+		var m = level.Monsters[pos]
+		s.Monsters[pos] = Actor{copyCharacter(m.Character), ids[&m.Character]}
+	}
+	s.Others = game.others(level.Player)
+
+	for pos, items := range level.Items {
+		var copies = make([]Item, len(items))
+		for i, item := range items {
+			copies[i] = *item
+		}
+		s.Items[pos] = copies
+	}
+	game.last = s
+	return s
+}
+
+// viewFor is s as p sees it, with p as the Player and the first player
+// among the Others instead.
+func (game *Game) viewFor(s *Snapshot, p *Player) *Snapshot {
+	if p == game.World.Player {
+		return s
+	}
+	var view = *s
+	view.Player = copyPlayer(p)
+	view.Others = game.others(p)
+	return &view
+}
+
+func (game *Game) others(viewer *Player) map[Position]Actor {
+	var others = make(map[Position]Actor)
+	for _, p := range game.World.Players {
+		if p != viewer && p.Hitpoints > 0 {
+			others[p.Position] = Actor{copyCharacter(p.Character), game.ids[&p.Character]}
+		}
+	}
+	return others
+}
+
+func copyItem(item *Item) *Item {
+	if item == nil {
+		return nil
+	}
+	var c = *item
+	return &c
+}
+
+func copyCharacter(c Character) Character {
+	c.Weapon = copyItem(c.Weapon)
+	c.Armor = copyItem(c.Armor)
+	return c
+}
+
+func copyPlayer(p *Player) Player {
+	var c = *p
+	c.Character = copyCharacter(p.Character)
+	c.Inventory = make([]*Item, len(p.Inventory))
+	for i, item := range p.Inventory {
+		c.Inventory[i] = copyItem(item)
+	}
+	return c
+}
@@ -0,0 +1,181 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// readSnapshot goes through everything in s the way a view does.
+func readSnapshot(t *testing.T, s, prev *Snapshot) {
+	for y := 0; y < s.Height(); y++ {
+		for x := 0; x < s.Width(); x++ {
+			s.Tile(Position{x, y})
+		}
+	}
+	for _, actors := range []map[Position]Actor{s.Monsters, s.Others} {
+		for _, a := range actors {
+			_ = a.Hitpoints + a.GetAttackPower()
+		}
+	}
+	for _, items := range s.Items {
+		for _, item := range items {
+			_ = item.Name
+		}
+	}
+	for _, item := range s.Player.Inventory {
+		_ = item.Name
+	}
+	s.Messages(10)
+	s.Diff(prev)
+	d, err := s.Delta(prev)
+	if err == nil {
+		_, err = json.Marshal(d)
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestSnapshotRace has views read snapshots while Run keeps playing, run
+// it with -race.
+func TestSnapshotRace(t *testing.T) {
+	var (
+		game        = newTestGame(t)
+		host, guest = game.LevelChans[0], NewLevelChan()
+		read        = make(chan int)
+		inputs      = []*Input{{Type: Join, LevelChannel: host}, {Type: Join, LevelChannel: guest}}
+		moves       = []InputType{Right, Right, Down, None, Left, Up, None}
+		reader      = func(view chan *Snapshot) {
+			var prev *Snapshot
+			var n = 0
+			for {
+				select {
+				case s := <-view:
+					readSnapshot(t, s, prev)
+					prev = s
+					n++
+				case <-game.Done():
+					read <- n
+					return
+				}
+			}
+		}
+	)
+	for i := 0; i < 100; i++ {
+		inputs = append(inputs,
+			&Input{Type: moves[i%len(moves)], LevelChannel: host},
+			&Input{Type: moves[(i+3)%len(moves)], LevelChannel: guest})
+	}
+	go reader(host)
+	go reader(guest)
+	runGame(t, game, inputs...)
+	for i := 0; i < 2; i++ {
+		if n := <-read; n == 0 {
+			t.Error("a view got no snapshots")
+		}
+	}
+}
+
+// roundTrip sends d through JSON and applies it to prev.
+func roundTrip(t *testing.T, d Delta, prev *Snapshot) *Snapshot {
+	t.Helper()
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Delta
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	s, err := decoded.Apply(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func sameSnapshot(t *testing.T, got, want *Snapshot) {
+	t.Helper()
+	if got.Version != want.Version || got.Turn != want.Turn || got.Depth != want.Depth || got.Name != want.Name || got.GameOver != want.GameOver {
+		t.Errorf("version %d turn %d depth %d %q, want version %d turn %d depth %d %q",
+			got.Version, got.Turn, got.Depth, got.Name, want.Version, want.Turn, want.Depth, want.Name)
+	}
+	if got.Width() != want.Width() || got.Height() != want.Height() {
+		t.Fatalf("%dx%d, want %dx%d", got.Width(), got.Height(), want.Width(), want.Height())
+	}
+	for y := 0; y < want.Height(); y++ {
+		for x := 0; x < want.Width(); x++ {
+			if pos := (Position{x, y}); got.Tile(pos) != want.Tile(pos) {
+				t.Errorf("tile at %v is %+v, want %+v", pos, got.Tile(pos), want.Tile(pos))
+			}
+		}
+	}
+	for name, pair := range map[string][2]interface{}{
+		"player":   {got.Player, want.Player},
+		"monsters": {got.Monsters, want.Monsters},
+		"others":   {got.Others, want.Others},
+		"items":    {got.Items, want.Items},
+		"messages": {got.Messages(eventHistory), want.Messages(eventHistory)},
+	} {
+		if !reflect.DeepEqual(pair[0], pair[1]) {
+			t.Errorf("%s %+v, want %+v", name, pair[0], pair[1])
+		}
+	}
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	var (
+		game  = newTestGame(t)
+		first = game.snapshot()
+		sent  = roundTrip(t, mustDelta(t, first, nil), nil)
+	)
+	sameSnapshot(t, sent, first)
+
+	var prev = first
+	for _, input := range []InputType{Right, Right, Down, None, None, Left} {
+		if _, err := game.Simulator.Step(Input{Type: input}); err != nil {
+			t.Fatal(err)
+		}
+		var (
+			s = game.snapshot()
+			d = mustDelta(t, s, prev)
+		)
+		if d.Full || d.Base != prev.Version || len(d.Map) != 0 {
+			t.Errorf("turn %d: delta is full or on the wrong base", s.Turn)
+		}
+		if len(d.Tiles) >= s.Width()*s.Height()/2 {
+			t.Errorf("turn %d: %d tiles changed", s.Turn, len(d.Tiles))
+		}
+		sent = roundTrip(t, d, sent)
+		sameSnapshot(t, sent, s)
+		prev = s
+	}
+
+	// a delta doesn't go on anything but its base
+	var d = mustDelta(t, game.snapshot(), prev)
+	if _, err := d.Apply(first); err != ErrDeltaBase {
+		t.Errorf("applied to the wrong snapshot: %v", err)
+	}
+	if _, err := d.Apply(nil); err != ErrDeltaBase {
+		t.Errorf("applied to nothing: %v", err)
+	}
+
+	// nor does a level of ragged rows
+	d = mustDelta(t, first, nil)
+	d.Map = append([][]Tile(nil), d.Map...)
+	d.Map[1] = d.Map[1][:len(d.Map[1])-1]
+	if _, err := d.Apply(nil); err == nil || err.Error() != fmt.Sprintf("game: delta row 1 is %d tiles wide, not %d", first.Width()-1, first.Width()) {
+		t.Errorf("applied ragged rows: %v", err)
+	}
+}
+
+func mustDelta(t *testing.T, s, prev *Snapshot) Delta {
+	t.Helper()
+	d, err := s.Delta(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
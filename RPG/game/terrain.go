@@ -0,0 +1,51 @@
+package game
+
+import "experiments/experiments/RPG/pathfind"
+
+// TerrainCosts weigh paths and multiply Costs.Move for stepping onto a
+// tile. Tiles that aren't listed cost 1. A closed door counts opening it
+// and stepping through.
+var TerrainCosts = map[Title]float64{
+	Mud:       2,
+	Water:     3,
+	CloseDoor: 2,
+}
+
+func terrainCost(level *Level, pos Position) float64 {
+	if !inRange(level, pos) {
+		return 1
+	}
+	if cost, ok := TerrainCosts[level.Map[pos.Y][pos.X]]; ok {
+		return cost
+	}
+	return 1
+}
+
+func minTerrainCost() float64 {
+	var lowest = 1.0
+	for _, cost := range TerrainCosts {
+		if cost < lowest {
+			lowest = cost
+		}
+	}
+	return lowest
+}
+
+// grid is the level as a pathfind graph over the tiles passable allows.
+func (level *Level) grid(passable func(*Level, Position) bool) pathfind.Grid {
+	var height, width = len(level.Map), 0
+	if height > 0 {
+		width = len(level.Map[0])
+	}
+	return pathfind.Grid{
+		Width:  width,
+		Height: height,
+		Passable: func(p pathfind.Point) bool {
+			return passable(level, Position(p))
+		},
+		StepCost: func(p pathfind.Point) float64 {
+			return terrainCost(level, Position(p))
+		},
+		Diagonal: level.diagonal,
+	}
+}
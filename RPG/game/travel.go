@@ -0,0 +1,248 @@
+package game
+
+import (
+	"errors"
+	"experiments/experiments/RPG/pathfind"
+)
+
+var (
+	ErrNoPath           = errors.New("game: no known way there")
+	ErrNothingToExplore = errors.New("game: nothing left to explore")
+	ErrNotTraveling     = errors.New("game: not traveling")
+)
+
+// notices are what the player is told when a travel doesn't start or
+// runs out of places to explore.
+var notices = map[error]string{
+	ErrNoPath:           "No Known Way There",
+	ErrNothingToExplore: "Nothing Left To Explore",
+}
+
+// travel is a walk over several turns, to a clicked tile or, when
+// exploring, to whatever unexplored tile is nearest at each step.
+type travel struct {
+	path    []Position
+	explore bool
+	// seen are the monsters that were in view already
+	seen map[*Monster]bool
+}
+
+// Traveling is true while Continue has steps left to take.
+func (s *Simulator) Traveling() bool {
+	return len(s.travels) > 0
+}
+
+func (s *Simulator) StopTravel() {
+	s.travels = nil
+}
+
+func (s *Simulator) plan(p *Player, input Input) error {
+	var (
+		level  = s.World.Level()
+		player = p.Position
+		t      = &travel{explore: input.Type == Explore, seen: make(map[*Monster]bool)}
+	)
+	for pos, m := range level.Monsters {
+		if level.Visible[pos] {
+			t.seen[m] = true
+		}
+	}
+	if t.explore {
+		if _, ok := level.nearestUnexplored(player); !ok {
+			return s.notice(p, ErrNothingToExplore)
+		}
+	} else {
+		if t.path = level.knownPath(player, input.Target); t.path == nil {
+			return s.notice(p, ErrNoPath)
+		}
+	}
+	if s.travels == nil {
+		s.travels = make(map[*Player]*travel)
+	}
+	s.travels[p] = t
+	return nil
+}
+
+// Continue takes the next step of the travels in progress, one for each
+// player on the way in party order, and reports the last. A travel ends
+// at its goal, when a monster comes into view, on another level, when
+// the player dies, or as soon as anything but walking happens.
+func (s *Simulator) Continue() (StepResult, error) {
+	if len(s.travels) == 0 {
+		return StepResult{Turn: s.Turn, Level: s.World.Current}, ErrNotTraveling
+	}
+	var (
+		result StepResult
+		err    error
+	)
+	for _, p := range s.World.Players {
+		if s.travels[p] != nil {
+			result, err = s.continueTravel(p)
+		}
+	}
+	return result, err
+}
+
+func (s *Simulator) continueTravel(player *Player) (StepResult, error) {
+	var (
+		t     = s.travels[player]
+		level = s.World.Level()
+		none  = StepResult{Turn: s.Turn, Level: s.World.Current}
+	)
+	if t == nil {
+		return none, ErrNotTraveling
+	}
+	if s.GameOver {
+		delete(s.travels, player)
+		return StepResult{Turn: s.Turn, Level: s.World.Current, GameOver: true}, ErrGameOver
+	}
+	if player.Hitpoints <= 0 {
+		delete(s.travels, player)
+		return none, ErrPlayerDead
+	}
+	if t.explore {
+		goal, ok := level.nearestUnexplored(player.Position)
+		if !ok {
+			delete(s.travels, player)
+			return none, s.notice(player, ErrNothingToExplore)
+		}
+		t.path = level.knownPath(player.Position, goal)
+	}
+	if len(t.path) == 0 {
+		delete(s.travels, player)
+		return none, ErrNoPath
+	}
+	var next = t.path[0]
+	dir, ok := direction(player.Position, next)
+	if !ok {
+		delete(s.travels, player)
+		return none, ErrNoPath
+	}
+
+	var interrupted = false
+	var unsubscribe = level.Events.Subscribe(func(e Event) {
+		switch e := e.(type) {
+		case MoveEvent:
+		case DoorOpenedEvent:
+			interrupted = interrupted || !e.Actor.Player
+		default:
+			interrupted = true
+		}
+	})
+	result, err := s.turn(player, Input{Type: dir})
+	unsubscribe()
+	if err != nil {
+		delete(s.travels, player)
+		return result, err
+	}
+
+	switch {
+	case player.Position == next:
+		t.path = t.path[1:]
+	case level.Map[next.Y][next.X] != OpenDoor || level.playerAt(next) != nil:
+		// something is in the way, maybe someone of the party; a door
+		// just opened is fine
+		interrupted = true
+	}
+	if interrupted || result.GameOver || s.World.Level() != level ||
+		!t.explore && len(t.path) == 0 || t.monsterCameIntoView(level) || player.Hitpoints <= 0 {
+		delete(s.travels, player)
+	}
+	return result, nil
+}
+
+func (t *travel) monsterCameIntoView(level *Level) bool {
+	var appeared = false
+	for pos, m := range level.Monsters {
+		if level.Visible[pos] && !t.seen[m] {
+			t.seen[m] = true
+			appeared = true
+		}
+	}
+	return appeared
+}
+
+func direction(from, to Position) (InputType, bool) {
+	switch (Position{to.X - from.X, to.Y - from.Y}) {
+	case Position{0, -1}:
+		return Up, true
+	case Position{0, 1}:
+		return Down, true
+	case Position{-1, 0}:
+		return Left, true
+	case Position{1, 0}:
+		return Right, true
+	case Position{-1, -1}:
+		return UpLeft, true
+	case Position{1, -1}:
+		return UpRight, true
+	case Position{-1, 1}:
+		return DownLeft, true
+	case Position{1, 1}:
+		return DownRight, true
+	}
+	return None, false
+}
+
+// knownGrid only goes where the player has been or seen.
+func (level *Level) knownGrid() pathfind.Grid {
+	return level.grid(func(level *Level, pos Position) bool {
+		return level.Explored[pos] && canPass(level, pos)
+	})
+}
+
+// knownPath are the steps from start to goal over explored tiles,
+// start excluded. Nil if there's no such way.
+func (level *Level) knownPath(start, goal Position) []Position {
+	if start == goal || !level.Explored[goal] {
+		return nil
+	}
+	var grid = level.knownGrid()
+	var path, _ = pathfind.AStar(grid, pathfind.Point(start), pathfind.Point(goal), grid.Heuristic(minTerrainCost()))
+	if len(path) < 2 {
+		return nil
+	}
+	var steps = make([]Position, len(path)-1)
+	for i, p := range path[1:] {
+		steps[i] = Position(p)
+	}
+	return steps
+}
+
+// nearestUnexplored is the cheapest explored tile to walk to that borders
+// on an unexplored one, ties broken in reading order.
+func (level *Level) nearestUnexplored(start Position) (Position, bool) {
+	var (
+		dist     = pathfind.Dijkstra(level.knownGrid(), pathfind.Point(start))
+		best     Position
+		bestDist float64
+		found    = false
+	)
+	for y, row := range level.Map {
+		for x := range row {
+			var pos = Position{x, y}
+			d, reached := dist[pathfind.Point(pos)]
+			if !reached || d == 0 || found && d >= bestDist || !level.bordersUnexplored(pos) {
+				continue
+			}
+			best, bestDist, found = pos, d, true
+		}
+	}
+	return best, found
+}
+
+func (level *Level) bordersUnexplored(pos Position) bool {
+	for _, d := range level.steps() {
+		if next := (Position{pos.X + d.X, pos.Y + d.Y}); inRange(level, next) && !level.Explored[next] {
+			return true
+		}
+	}
+	return false
+}
+
+// notice publishes the notice for err to p and returns err.
+func (s *Simulator) notice(p *Player, err error) error {
+	var level = s.World.Level()
+	level.publish(NoticeEvent{level.header(p.Position), participant(&p.Character, level), notices[err]})
+	return err
+}
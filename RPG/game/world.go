@@ -0,0 +1,141 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// World is a dungeon of levels stacked top to bottom. Every level keeps its
+// own monsters, doors and events while the player is elsewhere; only the
+// current level is simulated.
+type World struct {
+	Levels  []*Level
+	Current int
+	Player  *Player
+	// Players is the party, Player first. It stays together: whoever
+	// takes the stairs takes everyone along.
+	Players []*Player
+	// Combat resolves every fight on every level
+	Combat *Resolver
+	// Diagonal lets everyone step diagonally as long as they don't cut a
+	// corner, see SetDiagonal. It's off unless a game turns it on.
+	Diagonal bool
+}
+
+// NewWorld links the levels in order: DownStair on Levels[i] leads to
+// UpStair on Levels[i+1]. The player starts on the first level.
+func NewWorld(levels ...*Level) (*World, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("game: world has no levels")
+	}
+	if !levels[0].hasStart {
+		return nil, fmt.Errorf("game: first level %q has no player start", levels[0].Name)
+	}
+	var world = &World{Levels: levels, Player: levels[0].Player}
+	world.setParty([]*Player{world.Player})
+	world.SetCombat(NewResolver(DefaultRules, NewRNG(1)))
+	world.Level().updateVision()
+	return world, nil
+}
+
+// SetCombat swaps the combat rules and generator for all levels,
+// e.g. to seed a new game.
+func (world *World) SetCombat(combat *Resolver) {
+	world.Combat = combat
+	for _, level := range world.Levels {
+		level.combat = combat
+	}
+}
+
+// SetDiagonal turns diagonal steps on or off for all levels: the moves,
+// the paths, the monsters' neighbors and distances all follow.
+func (world *World) SetDiagonal(on bool) {
+	world.Diagonal = on
+	for _, level := range world.Levels {
+		level.diagonal = on
+	}
+}
+
+func (world *World) Level() *Level {
+	return world.Levels[world.Current]
+}
+
+// takeStairs moves the party to the next or previous level if p is
+// standing on stairs leading there, the others to the free spots nearest
+// to p. Reports whether the level changed.
+func (world *World) takeStairs(p *Player) bool {
+	var (
+		from   = world.Level()
+		pos    = p.Position
+		target int
+		arrive Title
+	)
+	switch from.Map[pos.Y][pos.X] {
+	case DownStair:
+		target, arrive = world.Current+1, UpStair
+	case UpStair:
+		target, arrive = world.Current-1, DownStair
+	default:
+		return false
+	}
+	if target < 0 || target >= len(world.Levels) {
+		return false
+	}
+
+	var (
+		to          = world.Levels[target]
+		arrival, ok = to.findTile(arrive)
+	)
+	if !ok {
+		arrival = to.start
+	}
+	arrival = to.freeSpot(arrival, nil)
+
+	var current = world.Current
+	from.publish(StairsEvent{from.header(pos), participant(&p.Character, from), current, target})
+	world.Current = target
+	p.Position = arrival
+	var taken = map[Position]bool{arrival: true}
+	for _, other := range world.Players {
+		if other != p {
+			other.Position = to.freeSpot(arrival, taken)
+			taken[other.Position] = true
+		}
+	}
+	to.turn = from.turn
+	to.publish(StairsEvent{to.header(arrival), participant(&p.Character, to), current, target})
+	return true
+}
+
+// findTile returns the first tile of type t in reading order.
+func (level *Level) findTile(t Title) (Position, bool) {
+	for y, row := range level.Map {
+		for x, tile := range row {
+			if tile == t {
+				return Position{x, y}, true
+			}
+		}
+	}
+	return Position{}, false
+}
+
+// freeSpot returns pos, or the nearest walkable tile without a monster
+// that isn't taken either.
+func (level *Level) freeSpot(pos Position, taken map[Position]bool) Position {
+	var frontier = []Position{pos}
+	var visited = map[Position]bool{pos: true}
+	for len(frontier) > 0 {
+		var current = frontier[0]
+		frontier = frontier[1:]
+		if _, monster := level.Monsters[current]; !monster && !taken[current] && canWalk(level, current) {
+			return current
+		}
+		for _, next := range getNeighbors(level, current) {
+			if !visited[next] {
+				frontier = append(frontier, next)
+				visited[next] = true
+			}
+		}
+	}
+	return pos
+}
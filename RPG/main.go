@@ -2,13 +2,23 @@ package main
 
 import (
 	"experiments/experiments/RPG/game"
+	"experiments/experiments/RPG/play"
 	"experiments/experiments/RPG/ui2d"
+	"experiments/experiments/assets"
+	"flag"
+	"fmt"
+	"os"
 )
 
 func main() {
-	g := game.NewGame(1, "C:/Users/xpoc_/go/src/experiments/experiments/RPG/game/maps/level_1.map")
-
-	go g.Run()
-
-	ui2d.NewUI(g.InputChan, g.LevelChans[0]).Run()
+	var opts = play.Flags()
+	flag.Parse()
+	var loader = play.Assets(assets.Mounts{"ui2d": ui2d.Files})
+	err := play.Run(opts, func(inputChan chan *game.Input, levelChan chan *game.Snapshot, bestiary game.Bestiary) (play.UI, error) {
+		return ui2d.NewUI(inputChan, levelChan, loader, bestiary)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,157 @@
+package netplay
+
+import (
+	"encoding/json"
+	"experiments/experiments/RPG/game"
+	"fmt"
+	"net"
+)
+
+// Client stands in for a game served by Serve: a ui takes InputChan and
+// LevelChan as it would the game's. Windows the ui opens are fed here
+// from the same snapshots, QuitGame or closing the last one hangs up,
+// and every other input goes to the server for the client's player.
+type Client struct {
+	InputChan chan *game.Input
+	LevelChan chan *game.Snapshot
+
+	conn net.Conn
+	// err is why the connection ended, if it did before the ui quit; lost
+	// is receive's, handed over once it has closed its channel
+	err  error
+	lost error
+}
+
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient plays over conn, which it closes once the ui is done.
+func NewClient(conn net.Conn) *Client {
+	var c = &Client{
+		InputChan: make(chan *game.Input),
+		LevelChan: game.NewLevelChan(),
+		conn:      conn,
+	}
+	go c.run()
+	return c
+}
+
+func (c *Client) run() {
+	var (
+		done      = make(chan bool)
+		snapshots = c.receive(done)
+		views     = []chan *game.Snapshot{c.LevelChan}
+		latest    *game.Snapshot
+		encoder   = json.NewEncoder(c.conn)
+	)
+	defer c.conn.Close()
+	defer close(done)
+	for {
+		select {
+		case s, ok := <-snapshots:
+			if !ok {
+				// the views keep showing how the game was left
+				snapshots = nil
+				if c.err == nil {
+					c.err = c.lost
+				}
+				continue
+			}
+			latest = s
+			for _, view := range views {
+				send(view, s)
+			}
+		case input := <-c.InputChan:
+			switch input.Type {
+			case game.QuitGame:
+				return
+			case game.OpenWindow:
+				views = append(views, input.LevelChannel)
+				if latest != nil {
+					send(input.LevelChannel, latest)
+				}
+			case game.CloseWindow:
+				for i, view := range views {
+					if view == input.LevelChannel {
+						close(view)
+						views = append(views[:i], views[i+1:]...)
+						break
+					}
+				}
+				if len(views) == 0 {
+					return
+				}
+			default:
+				if snapshots == nil {
+					continue
+				}
+				if err := encoder.Encode(input); err != nil && c.err == nil {
+					c.err = err
+				}
+			}
+		}
+	}
+}
+
+// Err is why the connection ended while the ui was running, nil if it
+// didn't. It's only good once the ui has quit.
+func (c *Client) Err() error {
+	return c.err
+}
+
+// receive puts the server's deltas back together into snapshots until
+// the connection fails or done is closed.
+func (c *Client) receive(done chan bool) <-chan *game.Snapshot {
+	var snapshots = make(chan *game.Snapshot)
+	go func() {
+		defer close(snapshots)
+		var (
+			decoder = json.NewDecoder(c.conn)
+			s       *game.Snapshot
+		)
+		for {
+			var d game.Delta
+			err := decoder.Decode(&d)
+			if err == nil {
+				s, err = d.Apply(s)
+			}
+			if err != nil {
+				select {
+				case <-done:
+				default:
+					c.lost = fmt.Errorf("netplay: connection lost: %w", err)
+				}
+				return
+			}
+			select {
+			case snapshots <- s:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return snapshots
+}
+
+// send is the game's: the view gets s in place of one it hasn't taken,
+// nothing ever waits on a ui.
+func send(view chan *game.Snapshot, s *game.Snapshot) {
+	select {
+	case view <- s:
+		return
+	default:
+	}
+	select {
+	case <-view:
+	default:
+	}
+	select {
+	case view <- s:
+	default:
+	}
+}
@@ -0,0 +1,180 @@
+package netplay
+
+import (
+	"encoding/json"
+	"experiments/experiments/RPG/game"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// next is the first snapshot from view that ok accepts.
+func next(t *testing.T, view chan *game.Snapshot, ok func(*game.Snapshot) bool) *game.Snapshot {
+	t.Helper()
+	var timeout = time.After(5 * time.Second)
+	for {
+		select {
+		case s, open := <-view:
+			if !open {
+				t.Fatal("the view closed")
+			}
+			if ok(s) {
+				return s
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for a snapshot")
+		}
+	}
+}
+
+func serveGame(t *testing.T) (*game.Game, net.Listener) {
+	t.Helper()
+	bestiary, err := game.StockBestiary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := game.NewGame(1, bestiary, "../game/maps/level_1.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go g.Run()
+	go Serve(l, g)
+	return g, l
+}
+
+// fakeClient plays over a bare connection and puts every delta it gets
+// back together the way Client does.
+type fakeClient struct {
+	conn      net.Conn
+	encoder   *json.Encoder
+	snapshots chan *game.Snapshot
+	// err is why the connection ended, once snapshots is closed
+	err error
+}
+
+func dialFake(t *testing.T, addr string) *fakeClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c = &fakeClient{conn: conn, encoder: json.NewEncoder(conn), snapshots: make(chan *game.Snapshot, 1000)}
+	go func() {
+		defer close(c.snapshots)
+		var (
+			decoder = json.NewDecoder(conn)
+			s       *game.Snapshot
+		)
+		for {
+			var d game.Delta
+			c.err = decoder.Decode(&d)
+			if c.err == nil {
+				s, c.err = d.Apply(s)
+			}
+			if c.err != nil {
+				return
+			}
+			c.snapshots <- s
+		}
+	}()
+	return c
+}
+
+func (c *fakeClient) play(t *testing.T, input game.InputType) {
+	t.Helper()
+	if err := c.encoder.Encode(&game.Input{Type: input}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoopback has two clients play a few turns each and checks that
+// they end up seeing the same game.
+func TestLoopback(t *testing.T) {
+	var g, l = serveGame(t)
+	defer l.Close()
+	var a, b = dialFake(t, l.Addr().String()), dialFake(t, l.Addr().String())
+	defer a.conn.Close()
+	defer b.conn.Close()
+
+	var moves = []game.InputType{game.Right, game.Up, game.None, game.Left, game.Down, game.None}
+	for _, input := range moves {
+		a.play(t, input)
+		b.play(t, input)
+	}
+	// every input is a turn, the joins aren't
+	var turns = 2 * len(moves)
+	var sa = next(t, a.snapshots, func(s *game.Snapshot) bool { return s.Turn == turns })
+	var sb = next(t, b.snapshots, func(s *game.Snapshot) bool { return s.Turn == turns })
+	sameGame(t, sa, sb)
+
+	g.InputChan <- &game.Input{Type: game.QuitGame}
+	for _, c := range []*fakeClient{a, b} {
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for range c.snapshots {
+		}
+		if ne, ok := c.err.(net.Error); ok && ne.Timeout() {
+			t.Error("still connected after the game ended")
+		}
+	}
+}
+
+// sameGame checks that a and b, two players' views of one turn, show the
+// same game: the same level and each other where the other sees them.
+func sameGame(t *testing.T, a, b *game.Snapshot) {
+	t.Helper()
+	if a.Version != b.Version || a.Turn != b.Turn || a.Depth != b.Depth || a.Name != b.Name {
+		t.Fatalf("version %d turn %d depth %d %q against version %d turn %d depth %d %q",
+			a.Version, a.Turn, a.Depth, a.Name, b.Version, b.Turn, b.Depth, b.Name)
+	}
+	if a.Width() != b.Width() || a.Height() != b.Height() {
+		t.Fatalf("%dx%d against %dx%d", a.Width(), a.Height(), b.Width(), b.Height())
+	}
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			if pos := (game.Position{x, y}); a.Tile(pos) != b.Tile(pos) {
+				t.Errorf("tile at %v is %+v against %+v", pos, a.Tile(pos), b.Tile(pos))
+			}
+		}
+	}
+	if !reflect.DeepEqual(a.Monsters, b.Monsters) || !reflect.DeepEqual(a.Items, b.Items) {
+		t.Errorf("monsters %+v items %+v against monsters %+v items %+v", a.Monsters, a.Items, b.Monsters, b.Items)
+	}
+	if !reflect.DeepEqual(a.Messages(10), b.Messages(10)) {
+		t.Errorf("messages %q against %q", a.Messages(10), b.Messages(10))
+	}
+	if a.Player.Position == b.Player.Position {
+		t.Fatalf("both players at %v", a.Player.Position)
+	}
+	// each sees the other as they see themselves
+	for _, pair := range [][2]*game.Snapshot{{a, b}, {b, a}} {
+		var self, other = pair[0], pair[1]
+		seen, ok := other.Others[self.Player.Position]
+		if !ok || !reflect.DeepEqual(seen.Character, self.Player.Character) {
+			t.Errorf("%v seen as %+v, is %+v", self.Player.Position, seen.Character, self.Player.Character)
+		}
+	}
+	if len(a.Others) != 1 || len(b.Others) != 1 {
+		t.Errorf("others %v and %v, want just each other", a.Others, b.Others)
+	}
+}
+
+// TestClient plays a turn through Dial's Client.
+func TestClient(t *testing.T) {
+	var g, l = serveGame(t)
+	defer l.Close()
+	defer func() { g.InputChan <- &game.Input{Type: game.QuitGame} }()
+	client, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { client.InputChan <- &game.Input{Type: game.QuitGame} }()
+
+	var joined = next(t, client.LevelChan, func(s *game.Snapshot) bool { return true })
+	client.InputChan <- &game.Input{Type: game.None}
+	next(t, client.LevelChan, func(s *game.Snapshot) bool { return s.Turn > joined.Turn })
+}
@@ -0,0 +1,89 @@
+// Package netplay plays one game with several players over TCP. Serve
+// runs next to Game.Run and gives every connection a player of its own,
+// Dial connects a ui to it as if it were the game. Both ends write JSON,
+// one value per line: the server game.Delta, the client game.Input.
+package netplay
+
+import (
+	"encoding/json"
+	"experiments/experiments/RPG/game"
+	"net"
+)
+
+// Serve accepts connections on l and joins each to g until l is closed,
+// then returns the error Accept returned. g's Run must be going for as
+// long as anyone's connected; once it returns they're hung up on.
+func Serve(l net.Listener, g *game.Game) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serve(conn, g)
+	}
+}
+
+// serve plays one connection: the player it joined with leaves with it.
+func serve(conn net.Conn, g *game.Game) {
+	defer conn.Close()
+	var view = game.NewLevelChan()
+	if !sendInput(g, &game.Input{Type: game.Join, LevelChannel: view}) {
+		return
+	}
+	go func() {
+		// the game never waits on view, a slow client misses snapshots
+		// and gets what changed since the last one it got
+		var (
+			encoder = json.NewEncoder(conn)
+			sent    *game.Snapshot
+		)
+		defer conn.Close()
+		for {
+			var (
+				s  *game.Snapshot
+				ok bool
+			)
+			select {
+			case s, ok = <-view:
+			case <-g.Done():
+			}
+			if !ok {
+				return
+			}
+			d, err := s.Delta(sent)
+			if err == nil {
+				err = encoder.Encode(d)
+			}
+			if err != nil {
+				return
+			}
+			sent = s
+		}
+	}()
+
+	// only the inputs with a name decode, a client can't quit the game
+	// or open windows on it
+	var decoder = json.NewDecoder(conn)
+	for {
+		var input game.Input
+		if err := decoder.Decode(&input); err != nil {
+			break
+		}
+		input.LevelChannel = view
+		if !sendInput(g, &input) {
+			return
+		}
+	}
+	sendInput(g, &game.Input{Type: game.CloseWindow, LevelChannel: view})
+}
+
+// sendInput hands g input unless its Run has returned, and reports
+// whether it did.
+func sendInput(g *game.Game, input *game.Input) bool {
+	select {
+	case g.InputChan <- input:
+		return true
+	case <-g.Done():
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package pathfind
+
+// Grid is a Graph over a Width x Height rectangle of tiles.
+type Grid struct {
+	Width, Height int
+	// Passable says whether a tile can be entered at all.
+	Passable func(Point) bool
+	// StepCost is the cost of entering a tile, nil means 1 everywhere.
+	StepCost func(Point) float64
+	// Diagonal allows 8-way moves. A diagonal step costs the same as a
+	// straight one, the way the game charges for it, and may not cut a
+	// corner: both tiles it passes between have to be passable.
+	Diagonal bool
+}
+
+// neighbor order is fixed so that ties always break the same way
+var (
+	straight = []Point{{1, 0}, {-1, 0}, {0, -1}, {0, 1}}
+	diagonal = []Point{{1, -1}, {-1, -1}, {1, 1}, {-1, 1}}
+)
+
+func (g Grid) In(p Point) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < g.Width && p.Y < g.Height
+}
+
+func (g Grid) passable(p Point) bool {
+	return g.In(p) && (g.Passable == nil || g.Passable(p))
+}
+
+func (g Grid) Neighbors(p Point) []Point {
+	var neighbors = make([]Point, 0, 8)
+	for _, d := range straight {
+		if next := (Point{p.X + d.X, p.Y + d.Y}); g.passable(next) {
+			neighbors = append(neighbors, next)
+		}
+	}
+	if !g.Diagonal {
+		return neighbors
+	}
+	for _, d := range diagonal {
+		var next = Point{p.X + d.X, p.Y + d.Y}
+		if g.passable(next) && g.passable(Point{p.X + d.X, p.Y}) && g.passable(Point{p.X, p.Y + d.Y}) {
+			neighbors = append(neighbors, next)
+		}
+	}
+	return neighbors
+}
+
+func (g Grid) Cost(a, b Point) float64 {
+	var cost = 1.0
+	if g.StepCost != nil {
+		cost = g.StepCost(b)
+	}
+	return cost
+}
+
+// Heuristic is Chebyshev for diagonal grids and Manhattan otherwise, scaled
+// by the cheapest tile cost so that it stays admissible.
+func (g Grid) Heuristic(minCost float64) Heuristic {
+	var h = Manhattan
+	if g.Diagonal {
+		h = Chebyshev
+	}
+	if minCost == 1 {
+		return h
+	}
+	return func(a, b Point) float64 {
+		return h(a, b) * minCost
+	}
+}
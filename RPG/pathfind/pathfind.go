@@ -0,0 +1,38 @@
+// Package pathfind searches graphs of grid points: A* for single paths,
+// Dijkstra maps for "distance to the nearest goal" fields and plain BFS
+// for flood fills. It knows nothing about the game, callers describe
+// their map through Graph or Grid.
+package pathfind
+
+import "math"
+
+type Point struct {
+	X, Y int
+}
+
+// Graph is what the searches walk. Cost is only asked for b in
+// Neighbors(a) and must be positive.
+type Graph interface {
+	Neighbors(p Point) []Point
+	Cost(a, b Point) float64
+}
+
+// Heuristic estimates the cost from a to b. For A* to find the cheapest
+// path it must never overestimate.
+type Heuristic func(a, b Point) float64
+
+// Manhattan is the heuristic for 4-way movement.
+func Manhattan(a, b Point) float64 {
+	return math.Abs(float64(a.X-b.X)) + math.Abs(float64(a.Y-b.Y))
+}
+
+// Chebyshev is the heuristic for 8-way movement where a diagonal step
+// costs as much as a straight one.
+func Chebyshev(a, b Point) float64 {
+	return math.Max(math.Abs(float64(a.X-b.X)), math.Abs(float64(a.Y-b.Y)))
+}
+
+// Zero turns A* into Dijkstra's algorithm.
+func Zero(a, b Point) float64 {
+	return 0
+}
@@ -0,0 +1,147 @@
+package pathfind
+
+import (
+	"math"
+	"testing"
+)
+
+// grid reads rows: # can't be entered, , costs 5 and anything else 1.
+func grid(diagonal bool, rows ...string) Grid {
+	return Grid{
+		Width:    len(rows[0]),
+		Height:   len(rows),
+		Diagonal: diagonal,
+		Passable: func(p Point) bool { return rows[p.Y][p.X] != '#' },
+		StepCost: func(p Point) float64 {
+			if rows[p.Y][p.X] == ',' {
+				return 5
+			}
+			return 1
+		},
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestAStar(t *testing.T) {
+	var tests = []struct {
+		name        string
+		diagonal    bool
+		rows        []string
+		start, goal Point
+		cost        float64 // 0 for no path
+		steps       int
+	}{
+		{"straight", false, []string{"....."}, Point{0, 0}, Point{4, 0}, 4, 4},
+		{"around a wall", false, []string{"...", ".#.", "..."}, Point{1, 0}, Point{1, 2}, 4, 4},
+		{"around mud", false, []string{"...", ".,.", "..."}, Point{1, 0}, Point{1, 2}, 4, 4},
+		{"through mud", false, []string{"#.#", "#,#", "#.#"}, Point{1, 0}, Point{1, 2}, 6, 2},
+		{"no path", false, []string{"..#..", "..#.."}, Point{0, 0}, Point{4, 1}, 0, 0},
+		{"no diagonals", false, []string{"...", "...", "..."}, Point{0, 0}, Point{2, 2}, 4, 4},
+		{"diagonal", true, []string{"...", "...", "..."}, Point{0, 0}, Point{2, 2}, 2, 2},
+		{"diagonal as cheap as straight", true, []string{"....", "...."}, Point{0, 0}, Point{3, 1}, 3, 3},
+		{"diagonal into mud", true, []string{"..", ".,"}, Point{0, 0}, Point{1, 1}, 5, 1},
+		{"diagonal around mud", true, []string{"...", ".,.", "..."}, Point{0, 0}, Point{2, 2}, 3, 3},
+		{"no corner cutting", true, []string{".#", ".."}, Point{0, 0}, Point{1, 1}, 2, 2},
+		{"no squeezing", true, []string{".#", "#."}, Point{0, 0}, Point{1, 1}, 0, 0},
+		{"start is goal", false, []string{"..."}, Point{1, 0}, Point{1, 0}, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var g = grid(test.diagonal, test.rows...)
+			path, cost := AStar(g, test.start, test.goal, g.Heuristic(1))
+			if test.start == test.goal {
+				if len(path) != 1 || cost != 0 {
+					t.Errorf("path %v cost %v, want just the start", path, cost)
+				}
+				return
+			}
+			if test.cost == 0 {
+				if path != nil {
+					t.Errorf("path %v, want none", path)
+				}
+				return
+			}
+			if !near(cost, test.cost) || len(path)-1 != test.steps {
+				t.Fatalf("path %v cost %v, want %d steps costing %v", path, cost, test.steps, test.cost)
+			}
+			if path[0] != test.start || path[len(path)-1] != test.goal {
+				t.Errorf("path %v doesn't go from %v to %v", path, test.start, test.goal)
+			}
+			// every step is a move the grid allows
+			var total = 0.0
+			for i := 1; i < len(path); i++ {
+				var allowed = false
+				for _, n := range g.Neighbors(path[i-1]) {
+					allowed = allowed || n == path[i]
+				}
+				if !allowed {
+					t.Errorf("%v to %v is not a step", path[i-1], path[i])
+				}
+				total += g.Cost(path[i-1], path[i])
+			}
+			if !near(total, cost) {
+				t.Errorf("steps cost %v, AStar said %v", total, cost)
+			}
+		})
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	var g = grid(true, "...", ".#.", "...")
+	// stepping diagonally past the wall would cut its corner
+	if n := g.Neighbors(Point{1, 0}); len(n) != 2 {
+		t.Errorf("neighbors of 1,0: %v", n)
+	}
+	if n := g.Neighbors(Point{0, 0}); len(n) != 2 {
+		t.Errorf("neighbors of 0,0: %v", n)
+	}
+	g.Diagonal = false
+	if n := g.Neighbors(Point{1, 0}); len(n) != 2 {
+		t.Errorf("4-way neighbors of 1,0: %v", n)
+	}
+}
+
+func TestDijkstra(t *testing.T) {
+	var (
+		g    = grid(false, ".....", ".###.", ".....")
+		dist = Dijkstra(g, Point{0, 0}, Point{4, 2})
+	)
+	for p, want := range map[Point]float64{{0, 0}: 0, {4, 2}: 0, {2, 0}: 2, {4, 0}: 2, {2, 2}: 2} {
+		if d, ok := dist[p]; !ok || d != want {
+			t.Errorf("distance at %v is %v, want %v", p, d, want)
+		}
+	}
+	if _, ok := dist[Point{2, 1}]; ok {
+		t.Error("reached a wall")
+	}
+
+	if next, ok := dist.Downhill(g, Point{1, 0}); !ok || next != (Point{0, 0}) {
+		t.Errorf("downhill from 1,0 is %v %v", next, ok)
+	}
+	if _, ok := dist.Downhill(g, Point{0, 0}); ok {
+		t.Error("went downhill from a source")
+	}
+	if next, ok := dist.Uphill(g, Point{0, 0}); !ok || dist[next] != 1 {
+		t.Errorf("uphill from 0,0 is %v %v", next, ok)
+	}
+}
+
+func TestBFS(t *testing.T) {
+	var (
+		g    = grid(false, "..,", "#.#", "...")
+		dist = BFS(g, Point{0, 0})
+	)
+	// mud costs more but is only one step
+	if dist[Point{2, 0}] != 2 || dist[Point{2, 2}] != 4 || len(dist) != 7 {
+		t.Errorf("BFS %v", dist)
+	}
+	if p, ok := Nearest(g, Point{0, 0}, func(p Point) bool { return p.Y == 2 }); !ok || p != (Point{1, 2}) {
+		t.Errorf("nearest on the last row is %v %v", p, ok)
+	}
+	if _, ok := Nearest(g, Point{0, 0}, func(p Point) bool { return p == Point{0, 1} }); ok {
+		t.Error("found a wall")
+	}
+}
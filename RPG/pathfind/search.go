@@ -0,0 +1,184 @@
+package pathfind
+
+import "container/heap"
+
+type item struct {
+	Point
+	priority float64
+	seq      int
+}
+
+// queue is a min-heap on priority; equal priorities come out in the
+// order they went in so that searches are deterministic.
+type queue struct {
+	items []item
+	seq   int
+}
+
+func (q *queue) Len() int { return len(q.items) }
+
+func (q *queue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	return a.priority < b.priority || a.priority == b.priority && a.seq < b.seq
+}
+
+func (q *queue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *queue) Push(x interface{}) { q.items = append(q.items, x.(item)) }
+
+func (q *queue) Pop() interface{} {
+	var last = q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
+
+func (q *queue) push(p Point, priority float64) {
+	q.seq++
+	heap.Push(q, item{p, priority, q.seq})
+}
+
+func (q *queue) pop() Point {
+	return heap.Pop(q).(item).Point
+}
+
+// AStar returns the cheapest path from start to goal, both included, and
+// its cost. The path is nil if goal can't be reached.
+func AStar(g Graph, start, goal Point, h Heuristic) ([]Point, float64) {
+	var (
+		frontier = &queue{}
+		cameFrom = map[Point]Point{start: start}
+		cost     = map[Point]float64{start: 0}
+		closed   = make(map[Point]bool)
+	)
+	frontier.push(start, h(start, goal))
+	for frontier.Len() > 0 {
+		var current = frontier.pop()
+		if current == goal {
+			return walkBack(cameFrom, start, goal), cost[goal]
+		}
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+		for _, next := range g.Neighbors(current) {
+			var newCost = cost[current] + g.Cost(current, next)
+			if old, seen := cost[next]; !seen || newCost < old {
+				cost[next] = newCost
+				cameFrom[next] = current
+				frontier.push(next, newCost+h(next, goal))
+			}
+		}
+	}
+	return nil, 0
+}
+
+func walkBack(cameFrom map[Point]Point, start, goal Point) []Point {
+	var path = []Point{goal}
+	for pos := goal; pos != start; {
+		pos = cameFrom[pos]
+		path = append(path, pos)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// DistanceMap holds for every reached point the cost of the cheapest path
+// to the nearest source, a "Dijkstra map".
+type DistanceMap map[Point]float64
+
+// Dijkstra builds the DistanceMap of g from sources.
+func Dijkstra(g Graph, sources ...Point) DistanceMap {
+	var (
+		frontier = &queue{}
+		dist     = make(DistanceMap)
+		closed   = make(map[Point]bool)
+	)
+	for _, s := range sources {
+		dist[s] = 0
+		frontier.push(s, 0)
+	}
+	for frontier.Len() > 0 {
+		var current = frontier.pop()
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+		for _, next := range g.Neighbors(current) {
+			var newCost = dist[current] + g.Cost(current, next)
+			if old, seen := dist[next]; !seen || newCost < old {
+				dist[next] = newCost
+				frontier.push(next, newCost)
+			}
+		}
+	}
+	return dist
+}
+
+// Downhill is the neighbor of p closest to a source, the next step when
+// chasing. ok is false when no neighbor is closer than p itself.
+func (m DistanceMap) Downhill(g Graph, p Point) (best Point, ok bool) {
+	var bestDist, reached = m[p]
+	for _, next := range g.Neighbors(p) {
+		if d, seen := m[next]; seen && (!reached || d < bestDist) {
+			best, bestDist, reached, ok = next, d, true, true
+		}
+	}
+	return best, ok
+}
+
+// Uphill is the neighbor of p farthest from every source, the next step
+// when fleeing. ok is false when no neighbor is farther than p itself.
+func (m DistanceMap) Uphill(g Graph, p Point) (best Point, ok bool) {
+	var bestDist = m[p]
+	for _, next := range g.Neighbors(p) {
+		if d, seen := m[next]; seen && d > bestDist {
+			best, bestDist, ok = next, d, true
+		}
+	}
+	return best, ok
+}
+
+// BFS counts the steps from start to every point it can reach,
+// ignoring costs.
+func BFS(g Graph, start Point) map[Point]int {
+	var (
+		frontier = []Point{start}
+		dist     = map[Point]int{start: 0}
+	)
+	for len(frontier) > 0 {
+		var current = frontier[0]
+		frontier = frontier[1:]
+		for _, next := range g.Neighbors(current) {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[current] + 1
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return dist
+}
+
+// Nearest is the point fewest steps from start, start included, for which
+// match is true.
+func Nearest(g Graph, start Point, match func(Point) bool) (Point, bool) {
+	var (
+		frontier = []Point{start}
+		visited  = map[Point]bool{start: true}
+	)
+	for len(frontier) > 0 {
+		var current = frontier[0]
+		frontier = frontier[1:]
+		if match(current) {
+			return current, true
+		}
+		for _, next := range g.Neighbors(current) {
+			if !visited[next] {
+				visited[next] = true
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return Point{}, false
+}
@@ -0,0 +1,177 @@
+// Package play is what the RPG's commands share: the flags, the assets
+// and setting a game up to play, watch, serve, join or verify. A command
+// only picks the ui.
+package play
+
+import (
+	"experiments/experiments/RPG/game"
+	"experiments/experiments/RPG/netplay"
+	"experiments/experiments/assets"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+)
+
+// Levels are the maps a new game is played on, BestiaryPath the monsters
+// in them.
+var (
+	Levels       = []string{"game/maps/entrance.map", "game/maps/level_2.map"}
+	BestiaryPath = "game/maps/bestiary.txt"
+)
+
+type Options struct {
+	Record   string
+	Replay   string
+	Verify   string
+	Serve    string
+	Connect  string
+	Diagonal bool
+}
+
+// Flags defines the options on the command line, read them after
+// flag.Parse.
+func Flags() *Options {
+	var opts = &Options{}
+	flag.StringVar(&opts.Record, "record", "", "write a replay of the game to `file`")
+	flag.StringVar(&opts.Replay, "replay", "", "watch the replay in `file` instead of playing")
+	flag.StringVar(&opts.Verify, "verify", "", "check that the replay in `file` still ends the same, without playing")
+	flag.StringVar(&opts.Serve, "serve", "", "let others join the game at `address`, e.g. :7777")
+	flag.StringVar(&opts.Connect, "connect", "", "join the game served at `address` instead")
+	flag.BoolVar(&opts.Diagonal, "diagonal", false, "let everyone step diagonally")
+	return opts
+}
+
+// Assets has the game open its files through a loader that also mounts
+// the ui's. Files under $RPG_ASSETS, the working directory, the
+// executable's directory or the source tree win over the embedded ones.
+func Assets(mounts assets.Mounts) *assets.Loader {
+	var all = assets.Mounts{"game": game.Files}
+	for name, files := range mounts {
+		all[name] = files
+	}
+	var loader = assets.NewLoader(all, assets.Roots("RPG_ASSETS", "experiments/experiments/RPG")...)
+	game.OpenAsset = loader.Open
+	return loader
+}
+
+type UI interface {
+	// Run plays until the player quits
+	Run() error
+}
+
+// NewUI makes the ui a player plays through: it sends inputChan their
+// inputs and shows the snapshots from levelChan.
+type NewUI func(inputChan chan *game.Input, levelChan chan *game.Snapshot, bestiary game.Bestiary) (UI, error)
+
+// Run does what opts say with newUI as the player's ui, call Assets
+// first. It prints how a game or replay ended and returns what went
+// wrong with the ui, the connection or the setup.
+func Run(opts *Options, newUI NewUI) error {
+	bestiary, err := game.LoadBestiary(BestiaryPath)
+	if err != nil {
+		return err
+	}
+	switch {
+	case opts.Verify != "":
+		r, err := readReplay(opts.Verify)
+		if err != nil {
+			return err
+		}
+		return r.Verify(bestiary)
+	case opts.Connect != "":
+		return join(opts.Connect, bestiary, newUI)
+	}
+	return host(opts, bestiary, newUI)
+}
+
+// join plays on a game served elsewhere.
+func join(addr string, bestiary game.Bestiary, newUI NewUI) error {
+	client, err := netplay.Dial(addr)
+	if err != nil {
+		return err
+	}
+	ui, err := newUI(client.InputChan, client.LevelChan, bestiary)
+	if err != nil {
+		client.InputChan <- &game.Input{Type: game.QuitGame}
+		return err
+	}
+	if err = ui.Run(); err == nil {
+		err = client.Err()
+	}
+	return err
+}
+
+// host plays a game of its own or a replay, serving it if asked to.
+func host(opts *Options, bestiary game.Bestiary, newUI NewUI) error {
+	var (
+		g   *game.Game
+		run func() error
+		err error
+	)
+	if opts.Replay != "" {
+		var r *game.Replay
+		if r, err = readReplay(opts.Replay); err != nil {
+			return err
+		}
+		if g, err = r.NewGame(1, bestiary); err != nil {
+			return err
+		}
+		run = func() error { return r.Play(g, 1) }
+	} else {
+		if g, err = game.NewGame(1, bestiary, Levels...); err != nil {
+			return err
+		}
+		g.World.SetDiagonal(opts.Diagonal)
+		if opts.Record != "" {
+			file, err := os.Create(opts.Record)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			if err := g.Record(file); err != nil {
+				return err
+			}
+		}
+		run = g.Run
+	}
+
+	// Run appends to LevelChans, the host's view is taken before it starts
+	var (
+		view = g.LevelChans[0]
+		done = make(chan error)
+	)
+	ui, err := newUI(g.InputChan, view, bestiary)
+	if err != nil {
+		return err
+	}
+	if opts.Serve != "" {
+		l, err := net.Listen("tcp", opts.Serve)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		go netplay.Serve(l, g)
+	}
+	go func() { done <- run() }()
+	// the host plays a player of their own like everyone else
+	g.InputChan <- &game.Input{Type: game.Join, LevelChannel: view}
+
+	var uiErr = ui.Run()
+	// the game finishes the replay it records or checks once the ui quits
+	if err := <-done; err != nil {
+		fmt.Println(err)
+	} else if opts.Replay != "" {
+		fmt.Println("The replay ended in the recorded state")
+	}
+	return uiErr
+}
+
+func readReplay(path string) (*game.Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return game.ReadReplay(file)
+}
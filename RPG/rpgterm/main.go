@@ -0,0 +1,26 @@
+// Command rpgterm plays the RPG in a terminal, see uiterm. Unlike the
+// SDL build it needs no display or libraries, so it runs over SSH.
+package main
+
+import (
+	"experiments/experiments/RPG/game"
+	"experiments/experiments/RPG/play"
+	"experiments/experiments/RPG/uiterm"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var opts = play.Flags()
+	flag.Parse()
+	play.Assets(nil)
+	err := play.Run(opts, func(inputChan chan *game.Input, levelChan chan *game.Snapshot, bestiary game.Bestiary) (play.UI, error) {
+		return uiterm.NewUI(inputChan, levelChan, os.Stdin, os.Stdout), nil
+	})
+	if err != nil {
+		// stdout may be what failed
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,215 @@
+package ui2d
+
+import (
+	"experiments/experiments/RPG/game"
+	"github.com/veandco/go-sdl2/sdl"
+	"math"
+)
+
+// The game moves in whole turns; the ui catches up over the next frames.
+// Everything here runs on the frame clock, never on game time.
+const (
+	moveDuration  = 120 // ms to slide to a new tile
+	bumpDuration  = 150 // ms of an attacker's lunge
+	flashDuration = 200 // ms a hit target is tinted
+	bumpDistance  = 0.3 // tiles an attacker lunges
+	snapDistance  = 3   // tiles, anything moving farther jumps
+	cameraLag     = 120 // ms, the camera covers ~63% of the way in this time
+	cameraLimit   = 5   // tiles the player can stray from the center
+)
+
+// frameClock is read once per frame so that everything drawn in a frame
+// agrees on the time.
+type frameClock struct {
+	now uint32
+	dt  uint32 // since the previous frame
+}
+
+func (c *frameClock) tick() {
+	var t = sdl.GetTicks()
+	if c.now != 0 {
+		c.dt = t - c.now
+	}
+	c.now = t
+}
+
+// vec is a position in tiles.
+type vec struct {
+	x, y float64
+}
+
+func (a vec) add(b vec) vec {
+	return vec{a.x + b.x, a.y + b.y}
+}
+
+func (a vec) scale(f float64) vec {
+	return vec{a.x * f, a.y * f}
+}
+
+func lerp(a, b vec, t float64) vec {
+	return vec{a.x + (b.x-a.x)*t, a.y + (b.y-a.y)*t}
+}
+
+func tileVec(pos game.Position) vec {
+	return vec{float64(pos.X), float64(pos.Y)}
+}
+
+// actorAnim is how the ui shows a character between turns.
+type actorAnim struct {
+	from, to   vec
+	moveStart  uint32
+	bump       vec // unit direction of the last blow
+	bumpUntil  uint32
+	flashUntil uint32
+	// phase keeps monsters of a kind from idling in lockstep
+	phase uint32
+}
+
+// pos is where the actor is drawn at now, easing out towards to.
+func (a *actorAnim) pos(now uint32) vec {
+	var t = float64(now-a.moveStart) / moveDuration
+	if t >= 1 {
+		return a.to
+	}
+	return lerp(a.from, a.to, 1-(1-t)*(1-t))
+}
+
+// offset is the lunge of an attack, out and back.
+func (a *actorAnim) offset(now uint32) vec {
+	if now >= a.bumpUntil {
+		return vec{}
+	}
+	var t = 1 - float64(a.bumpUntil-now)/bumpDuration
+	return a.bump.scale(math.Sin(math.Pi*t) * bumpDistance)
+}
+
+func (a *actorAnim) flashing(now uint32) bool {
+	return now < a.flashUntil
+}
+
+// playerID keys the player's animation, monster IDs start at 1.
+const playerID = 0
+
+// observe catches the animations up with a snapshot the game just sent:
+// new positions start sliding and the blows since the last one start
+// bumps and flashes.
+func (ui *ui) observe(level *game.Snapshot) {
+	var (
+		now  = ui.clock.now
+		diff = level.Diff(ui.drawn)
+	)
+	ui.drawn = level
+	if diff.Full {
+		// the stairs or a restart, everything jumps into place
+		ui.actors = make(map[int]*actorAnim)
+		ui.cameraSet = false
+	}
+
+	var alive = map[int]bool{playerID: true}
+	ui.track(playerID, level.Player.Position, now)
+	for _, actors := range []map[game.Position]game.Actor{level.Monsters, level.Others} {
+		for _, a := range actors {
+			ui.track(a.ID, a.Position, now)
+			alive[a.ID] = true
+		}
+	}
+	for id := range ui.actors {
+		if !alive[id] {
+			delete(ui.actors, id)
+		}
+	}
+
+	for _, e := range diff.Events {
+		switch e := e.(type) {
+		case game.AttackEvent:
+			if a := ui.actorAt(level, e.Actor, e.From); a != nil {
+				var dx, dy = float64(e.Position.X - e.From.X), float64(e.Position.Y - e.From.Y)
+				if d := math.Hypot(dx, dy); d > 0 {
+					a.bump = vec{dx / d, dy / d}
+					a.bumpUntil = now + bumpDuration
+				}
+			}
+		case game.DamageEvent:
+			if a := ui.actorAt(level, e.Target, e.Position); a != nil {
+				a.flashUntil = now + flashDuration
+			}
+		}
+	}
+}
+
+func (ui *ui) track(id int, pos game.Position, now uint32) {
+	var to = tileVec(pos)
+	a, ok := ui.actors[id]
+	if !ok {
+		ui.actors[id] = &actorAnim{from: to, to: to, phase: uint32(pos.X*97 + pos.Y*61)}
+		return
+	}
+	if a.to == to {
+		return
+	}
+	var from = a.pos(now)
+	if math.Hypot(to.x-from.x, to.y-from.y) > snapDistance {
+		from = to
+	}
+	a.from, a.to, a.moveStart = from, to, now
+}
+
+// actorAt finds the animation of an event's participant, nil if it is
+// gone or has moved on.
+func (ui *ui) actorAt(level *game.Snapshot, p game.Participant, pos game.Position) *actorAnim {
+	if p.Player && p.Name == level.Player.Name {
+		return ui.actors[playerID]
+	}
+	var actors = level.Monsters
+	if p.Player {
+		actors = level.Others
+	}
+	if a, ok := actors[pos]; ok && a.Name == p.Name {
+		return ui.actors[a.ID]
+	}
+	return nil
+}
+
+// updateCamera keeps the player within cameraLimit tiles of the center
+// and eases the camera there.
+func (ui *ui) updateCamera(player game.Position) {
+	var clamp = func(center, p int) int {
+		if p > center+cameraLimit {
+			return p - cameraLimit
+		}
+		if p < center-cameraLimit {
+			return p + cameraLimit
+		}
+		return center
+	}
+	if !ui.cameraSet {
+		ui.centerX, ui.centerY = player.X, player.Y
+		ui.camera = tileVec(player)
+		ui.cameraSet = true
+	}
+	ui.centerX, ui.centerY = clamp(ui.centerX, player.X), clamp(ui.centerY, player.Y)
+	var k = 1 - math.Exp(-float64(ui.clock.dt)/cameraLag)
+	ui.camera = lerp(ui.camera, vec{float64(ui.centerX), float64(ui.centerY)}, k)
+}
+
+// drawActor draws the actor id standing at tile where its animation has
+// it, tinted while it flashes.
+func (ui *ui) drawActor(id int, tile game.Position, sprite *Sprite, offsetX, offsetY int32) error {
+	var (
+		now = ui.clock.now
+		pos = tileVec(tile)
+		src sdl.Rect
+	)
+	if a, ok := ui.actors[id]; ok {
+		pos = a.pos(now).add(a.offset(now))
+		src = sprite.At(now + a.phase)
+		if a.flashing(now) {
+			ui.textureAtlas.SetColorMod(255, 64, 64)
+			defer ui.textureAtlas.SetColorMod(255, 255, 255)
+		}
+	} else {
+		src = sprite.At(now)
+	}
+	var dst = sdl.Rect{int32(math.Round(pos.x*32)) + offsetX, int32(math.Round(pos.y*32)) + offsetY, 32, 32}
+	return ui.renderer.Copy(ui.textureAtlas, &src, &dst)
+}
@@ -0,0 +1,154 @@
+package ui2d
+
+import (
+	"encoding/json"
+	"experiments/experiments/RPG/game"
+	"math"
+	"testing"
+)
+
+func nearVec(a, b vec) bool {
+	return math.Abs(a.x-b.x) < 1e-9 && math.Abs(a.y-b.y) < 1e-9
+}
+
+// TestActorAnim follows an actor that steps two tiles right while it
+// lunges right and flashes, all starting at 1000 ms.
+func TestActorAnim(t *testing.T) {
+	var a = actorAnim{
+		from: vec{0, 0}, to: vec{2, 0}, moveStart: 1000,
+		bump: vec{1, 0}, bumpUntil: 1000 + bumpDuration,
+		flashUntil: 1000 + flashDuration,
+	}
+	var tests = []struct {
+		now      uint32
+		pos      vec
+		offset   vec
+		flashing bool
+	}{
+		{1000, vec{0, 0}, vec{0, 0}, true},
+		// half the time covers three quarters of the way
+		{1000 + moveDuration/2, vec{1.5, 0}, vec{math.Sin(math.Pi*60/bumpDuration) * bumpDistance, 0}, true},
+		// the lunge is farthest out halfway
+		{1000 + bumpDuration/2, vec{2 * (1 - 0.375*0.375), 0}, vec{bumpDistance, 0}, true},
+		{1000 + moveDuration, vec{2, 0}, vec{math.Sin(math.Pi*120/bumpDuration) * bumpDistance, 0}, true},
+		{1000 + bumpDuration, vec{2, 0}, vec{0, 0}, true},
+		{1000 + flashDuration, vec{2, 0}, vec{0, 0}, false},
+	}
+	for _, test := range tests {
+		if pos, offset := a.pos(test.now), a.offset(test.now); !nearVec(pos, test.pos) || !nearVec(offset, test.offset) || a.flashing(test.now) != test.flashing {
+			t.Errorf("at %d: pos %v offset %v flashing %v, want %v %v %v",
+				test.now, pos, offset, a.flashing(test.now), test.pos, test.offset, test.flashing)
+		}
+	}
+}
+
+func TestTrack(t *testing.T) {
+	var u = &ui{actors: make(map[int]*actorAnim)}
+	u.track(1, game.Position{0, 0}, 0)
+	var a = u.actors[1]
+	if a.pos(0) != (vec{0, 0}) {
+		t.Fatalf("a new actor is at %v", a.pos(0))
+	}
+	u.track(1, game.Position{1, 0}, 1000)
+	if a.from != (vec{0, 0}) || a.to != (vec{1, 0}) || a.moveStart != 1000 {
+		t.Errorf("step: %+v", a)
+	}
+	// stepping on mid-slide goes on from where it's drawn
+	u.track(1, game.Position{2, 0}, 1000+moveDuration/2)
+	if !nearVec(a.from, vec{0.75, 0}) || a.to != (vec{2, 0}) {
+		t.Errorf("step mid-slide: %+v", a)
+	}
+	// the same tile again changes nothing
+	u.track(1, game.Position{2, 0}, 1100)
+	if a.moveStart != 1000+moveDuration/2 {
+		t.Errorf("restarted the slide: %+v", a)
+	}
+	// stairs or a teleport jump
+	u.track(1, game.Position{9, 0}, 2000)
+	if a.from != (vec{9, 0}) || a.pos(2000) != (vec{9, 0}) {
+		t.Errorf("long move: %+v", a)
+	}
+}
+
+// testLevel is a one-row level, the player at 1 and the rat with ID 1 at ratX
+// if there is one. events are the delta's as JSON.
+func testLevel(t *testing.T, prev *game.Snapshot, version, ratX int, events string) *game.Snapshot {
+	t.Helper()
+	var d game.Delta
+	if err := json.Unmarshal([]byte(`{"events": [`+events+`]}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	d.Version, d.Turn = version, version
+	d.Player.Name, d.Player.Position = "GoMen", game.Position{1, 0}
+	if ratX > 0 {
+		d.Monsters = []game.Actor{{Character: game.Character{Entity: game.Entity{game.Position{ratX, 0}, "Rat", 'R'}}, ID: 1}}
+	}
+	if prev == nil {
+		d.Full = true
+		d.Map = [][]game.Tile{make([]game.Tile, 6)}
+	} else {
+		d.Base = prev.Version
+	}
+	s, err := d.Apply(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestObserve(t *testing.T) {
+	var u = &ui{actors: make(map[int]*actorAnim)}
+	u.clock.now = 500
+	var first = testLevel(t, nil, 1, 3, "")
+	u.observe(first)
+	if len(u.actors) != 2 || u.actors[1].to != (vec{3, 0}) || u.actors[playerID].to != (vec{1, 0}) {
+		t.Fatalf("actors %v", u.actors)
+	}
+
+	// the rat steps up and bites
+	u.clock.now = 1000
+	var second = testLevel(t, first, 2, 2,
+		`{"type": "attack", "event": {"Turn": 2, "Position": {"X": 1}, "Actor": {"Name": "Rat"}, "Target": {"Name": "GoMen", "Player": true}, "From": {"X": 2}, "Hit": true}},
+		{"type": "damage", "event": {"Turn": 2, "Position": {"X": 1}, "Actor": {"Name": "Rat"}, "Target": {"Name": "GoMen", "Player": true}, "Amount": 1}}`)
+	u.observe(second)
+	var rat, player = u.actors[1], u.actors[playerID]
+	if rat.from != (vec{3, 0}) || rat.to != (vec{2, 0}) || rat.moveStart != 1000 {
+		t.Errorf("rat doesn't slide: %+v", rat)
+	}
+	if rat.bump != (vec{-1, 0}) || rat.bumpUntil != 1000+bumpDuration || !player.flashing(1000) || player.bumpUntil != 0 {
+		t.Errorf("rat %+v, player %+v", rat, player)
+	}
+
+	// it dies
+	u.observe(testLevel(t, second, 3, 0, ""))
+	if _, ok := u.actors[1]; ok || len(u.actors) != 1 {
+		t.Errorf("the dead rat is still animated: %v", u.actors)
+	}
+
+	// a new level starts over
+	u.cameraSet = true
+	u.actors[playerID].flashUntil = 5000
+	u.observe(testLevel(t, nil, 4, 3, ""))
+	if u.cameraSet || u.actors[playerID].flashing(1000) || len(u.actors) != 2 {
+		t.Errorf("a full snapshot kept the animations: %v, camera set %v", u.actors, u.cameraSet)
+	}
+}
+
+func TestUpdateCamera(t *testing.T) {
+	var u = &ui{}
+	u.updateCamera(game.Position{10, 10})
+	if u.centerX != 10 || u.centerY != 10 || u.camera != (vec{10, 10}) {
+		t.Fatalf("camera starts at %v centered on %d,%d", u.camera, u.centerX, u.centerY)
+	}
+	// within the limit the center stays put
+	u.updateCamera(game.Position{10 + cameraLimit, 10 - cameraLimit})
+	if u.centerX != 10 || u.centerY != 10 || u.camera != (vec{10, 10}) {
+		t.Errorf("moved to %d,%d for a player within the limit", u.centerX, u.centerY)
+	}
+	// past it the center follows and the camera covers ~63% in cameraLag
+	u.clock.dt = cameraLag
+	u.updateCamera(game.Position{20, 10})
+	if u.centerX != 20-cameraLimit || !nearVec(u.camera, vec{10 + 5*(1-math.Exp(-1)), 10}) {
+		t.Errorf("center %d camera %v", u.centerX, u.camera)
+	}
+}
@@ -0,0 +1,365 @@
+package ui2d
+
+import (
+	"experiments/experiments/RPG/game"
+	"fmt"
+	"github.com/veandco/go-sdl2/sdl"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Atlas files name the sprites in the tile atlas image:
+//
+//	rpgatlas 1
+//	tile_size: 32x32
+//	columns: 63
+//
+//	# lines starting with # are comments
+//	[floor]
+//	glyph: .
+//	tile: 42,7
+//	variations: 7
+//
+//	[cracked floor]
+//	glyph: .
+//	rect: 1376,224,32,32
+//	weight: 2
+//
+//	[torch]
+//	glyph: |
+//	frames: 36,1 150; 37,1 150; 38,1 300
+//
+// tile_size is the size of an atlas cell in pixels and columns the width
+// of the image in cells, both only needed when they aren't 32x32 and 63.
+// A sprite is either a tile, counted in cells, a rect in pixels or a list
+// of frames, each a tile or rect with a duration in milliseconds. Sprites
+// sharing a glyph are random variations of it, picked by weight (1 unless
+// given); variations is a shorthand for that many tiles in a row of equal
+// weight, wrapping at columns.
+//
+// Files without the header are read in the old one-line-per-glyph format,
+// "<glyph> x,y,variations".
+const AtlasVersion = 1
+
+const atlasMagic = "rpgatlas"
+
+type Frame struct {
+	Src      sdl.Rect
+	Duration uint32 // milliseconds, 0 for a still
+}
+
+type Sprite struct {
+	Name   string
+	Glyph  game.Title // 0 for sprites only looked up by name
+	Weight int
+	Frames []Frame
+}
+
+// At is the frame to show ms milliseconds into a looping animation.
+func (s *Sprite) At(ms uint32) sdl.Rect {
+	var total uint32
+	for _, f := range s.Frames {
+		total += f.Duration
+	}
+	if total == 0 {
+		return s.Frames[0].Src
+	}
+	ms %= total
+	for _, f := range s.Frames {
+		if ms < f.Duration {
+			return f.Src
+		}
+		ms -= f.Duration
+	}
+	return s.Frames[len(s.Frames)-1].Src
+}
+
+type Atlas struct {
+	TileWidth, TileHeight int
+	Columns               int
+	Sprites               map[string]*Sprite
+	// Glyphs are the variations of every glyph, in file order
+	Glyphs map[game.Title][]*Sprite
+}
+
+func newAtlas() *Atlas {
+	return &Atlas{
+		TileWidth:  32,
+		TileHeight: 32,
+		Columns:    63,
+		Sprites:    make(map[string]*Sprite),
+		Glyphs:     make(map[game.Title][]*Sprite),
+	}
+}
+
+func (a *Atlas) tile(x, y int) sdl.Rect {
+	return sdl.Rect{int32(x * a.TileWidth), int32(y * a.TileHeight), int32(a.TileWidth), int32(a.TileHeight)}
+}
+
+func (a *Atlas) add(s *Sprite) {
+	if _, taken := a.Sprites[s.Name]; !taken {
+		a.Sprites[s.Name] = s
+	}
+	if s.Glyph != 0 {
+		a.Glyphs[s.Glyph] = append(a.Glyphs[s.Glyph], s)
+	}
+}
+
+// addRun adds count still variations of a glyph starting at cell x,y.
+func (a *Atlas) addRun(name string, glyph game.Title, x, y, count, weight int) {
+	for i := 0; i < count; i++ {
+		a.add(&Sprite{Name: name, Glyph: glyph, Weight: weight, Frames: []Frame{{a.tile(x, y), 0}}})
+		x++
+		if x >= a.Columns {
+			x = 0
+			y++
+		}
+	}
+}
+
+// Pick chooses a variation of glyph by weight.
+func (a *Atlas) Pick(glyph game.Title, r *rand.Rand) (*Sprite, bool) {
+	var variations = a.Glyphs[glyph]
+	if len(variations) == 0 {
+		return nil, false
+	}
+	var total = 0
+	for _, s := range variations {
+		total += s.Weight
+	}
+	var n = r.Intn(total)
+	for _, s := range variations {
+		if n < s.Weight {
+			return s, true
+		}
+		n -= s.Weight
+	}
+	return variations[len(variations)-1], true
+}
+
+// First is the first variation of glyph, for things that don't vary.
+func (a *Atlas) First(glyph game.Title) (*Sprite, bool) {
+	if variations := a.Glyphs[glyph]; len(variations) > 0 {
+		return variations[0], true
+	}
+	return nil, false
+}
+
+// AddMonsters gives every bestiary monster without a sprite of its own
+// glyph the one named after it or else the bestiary's sprite.
+func (a *Atlas) AddMonsters(defs []*game.MonsterDef) {
+	for _, def := range defs {
+		var glyph = game.Title(def.Glyph)
+		if _, ok := a.Glyphs[glyph]; ok {
+			continue
+		}
+		if named, ok := a.Sprites[def.Name]; ok {
+			var s = *named
+			s.Glyph = glyph
+			a.add(&s)
+		} else if def.Sprite.Variations > 0 {
+			a.addRun(def.Name, glyph, def.Sprite.X, def.Sprite.Y, def.Sprite.Variations, 1)
+		}
+	}
+}
+
+// ParseAtlas reads an atlas file in either format. Errors are
+// *game.MapError with File set to "atlas".
+func ParseAtlas(r io.Reader) (*Atlas, error) {
+	var lines = game.NewLineScanner(r, "atlas")
+	if !lines.Scan() && lines.Err() != nil {
+		return nil, lines.Err()
+	}
+	version, err := lines.Version(atlasMagic, AtlasVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return parseOldAtlas(lines)
+	}
+	return parseAtlas(lines)
+}
+
+// parseOldAtlas reads "<glyph> x,y,variations" lines, starting with the
+// one lines is at.
+func parseOldAtlas(lines *game.LineScanner) (*Atlas, error) {
+	var atlas = newAtlas()
+	for ok := lines.Line > 0; ok; ok = lines.Scan() {
+		var line = strings.TrimSpace(lines.Text)
+		if line == "" {
+			continue
+		}
+		var (
+			glyph       = []rune(line)[0]
+			x, y, count int
+		)
+		var rest = strings.Replace(line[len(string(glyph)):], " ", "", -1)
+		if _, err := fmt.Sscanf(rest, "%d,%d,%d", &x, &y, &count); err != nil || count < 1 {
+			return nil, lines.Errorf(0, "expected \"<glyph> x,y,variations\", got %q", line)
+		}
+		atlas.addRun(string(glyph), game.Title(glyph), x, y, count, 1)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	return atlas, nil
+}
+
+func parseAtlas(lines *game.LineScanner) (*Atlas, error) {
+	var (
+		atlas  = newAtlas()
+		sprite *Sprite
+		start  = 0 // line of sprite's section header
+		tile   *[2]int
+		count  = 1
+	)
+	var fail = func(line int, format string, args ...interface{}) error {
+		return &game.MapError{File: "atlas", Line: line, Msg: fmt.Sprintf(format, args...)}
+	}
+	var finish = func() error {
+		if sprite == nil {
+			return nil
+		}
+		switch {
+		case tile != nil && len(sprite.Frames) > 0:
+			return fail(start, "sprite %q has both a tile and a rect or frames", sprite.Name)
+		case tile != nil:
+			atlas.addRun(sprite.Name, sprite.Glyph, tile[0], tile[1], count, sprite.Weight)
+		case len(sprite.Frames) == 0:
+			return fail(start, "sprite %q needs a tile, rect or frames", sprite.Name)
+		case count != 1:
+			return fail(start, "sprite %q: variations only go with tile", sprite.Name)
+		default:
+			atlas.add(sprite)
+		}
+		return nil
+	}
+
+	for lines.Scan() {
+		if lines.Blank() {
+			continue
+		}
+		if name, ok := lines.Section(); ok {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if name == "" {
+				return nil, lines.Errorf(0, "sprite has no name")
+			}
+			if _, taken := atlas.Sprites[name]; taken {
+				return nil, lines.Errorf(0, "sprite %q is defined twice", name)
+			}
+			sprite, start, tile, count = &Sprite{Name: name, Weight: 1}, lines.Line, nil, 1
+			continue
+		}
+
+		key, value, column, err := lines.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+		if sprite == nil {
+			switch key {
+			case "tile_size":
+				if _, err = fmt.Sscanf(value, "%dx%d", &atlas.TileWidth, &atlas.TileHeight); err == nil &&
+					(atlas.TileWidth < 1 || atlas.TileHeight < 1) {
+					return nil, lines.Errorf(column, "tile_size must be above 0")
+				}
+			case "columns":
+				if atlas.Columns, err = strconv.Atoi(value); err == nil && atlas.Columns < 1 {
+					return nil, lines.Errorf(column, "columns must be above 0")
+				}
+			default:
+				return nil, lines.Errorf(0, "key %q outside of a [sprite] section", key)
+			}
+			if err != nil {
+				return nil, lines.Errorf(column, "bad %s %q", key, value)
+			}
+			continue
+		}
+
+		switch key {
+		case "glyph":
+			var runes = []rune(value)
+			if len(runes) != 1 {
+				return nil, lines.Errorf(column, "glyph must be a single character, got %q", value)
+			}
+			sprite.Glyph = game.Title(runes[0])
+		case "tile":
+			tile = new([2]int)
+			if _, err = fmt.Sscanf(value, "%d,%d", &tile[0], &tile[1]); err != nil {
+				return nil, lines.Errorf(column, "bad tile %q, expected x,y", value)
+			}
+		case "rect":
+			rect, ok := atlas.parseRect(value)
+			if !ok {
+				return nil, lines.Errorf(column, "bad rect %q, expected x,y,w,h", value)
+			}
+			sprite.Frames = append(sprite.Frames, Frame{rect, 0})
+		case "frames":
+			for _, part := range strings.Split(value, ";") {
+				var fields = strings.Fields(part)
+				if len(fields) == 0 || len(fields) > 2 {
+					return nil, lines.Errorf(column, "bad frame %q, expected \"x,y duration\"", strings.TrimSpace(part))
+				}
+				rect, ok := atlas.parseRect(fields[0])
+				if !ok {
+					return nil, lines.Errorf(column, "bad frame %q, expected x,y or x,y,w,h", fields[0])
+				}
+				var frame = Frame{Src: rect}
+				if len(fields) == 2 {
+					ms, err := strconv.ParseUint(strings.TrimSuffix(fields[1], "ms"), 10, 32)
+					if err != nil {
+						return nil, lines.Errorf(column, "bad frame duration %q", fields[1])
+					}
+					frame.Duration = uint32(ms)
+				}
+				sprite.Frames = append(sprite.Frames, frame)
+			}
+		case "variations":
+			if count, err = strconv.Atoi(value); err == nil && count < 1 {
+				return nil, lines.Errorf(column, "variations must be above 0")
+			}
+		case "weight":
+			if sprite.Weight, err = strconv.Atoi(value); err == nil && sprite.Weight < 1 {
+				return nil, lines.Errorf(column, "weight must be above 0")
+			}
+		default:
+			return nil, lines.Errorf(0, "unknown key %q", key)
+		}
+		if err != nil {
+			return nil, lines.Errorf(column, "bad %s %q", key, value)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return atlas, nil
+}
+
+// parseRect reads a cell "x,y" or pixels "x,y,w,h".
+func (a *Atlas) parseRect(value string) (sdl.Rect, bool) {
+	var parts = strings.Split(value, ",")
+	var n = make([]int32, len(parts))
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < 0 {
+			return sdl.Rect{}, false
+		}
+		n[i] = int32(v)
+	}
+	switch len(n) {
+	case 2:
+		return a.tile(int(n[0]), int(n[1])), true
+	case 4:
+		if n[2] == 0 || n[3] == 0 {
+			return sdl.Rect{}, false
+		}
+		return sdl.Rect{n[0], n[1], n[2], n[3]}, true
+	}
+	return sdl.Rect{}, false
+}
@@ -0,0 +1,197 @@
+package ui2d
+
+import (
+	"experiments/experiments/RPG/game"
+	"github.com/veandco/go-sdl2/sdl"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseAtlasErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"version", "rpgatlas 2\n", "atlas:1: unsupported atlas version 2"},
+		{"bad header", "rpgatlas x\n", "atlas:1: bad version in header"},
+		{"old format", "# 1,2,3\n. 1,2\n", `atlas:2: expected "<glyph> x,y,variations", got ". 1,2"`},
+		{"outside", "rpgatlas 1\nglyph: .\n", `atlas:2: key "glyph" outside of a [sprite] section`},
+		{"columns", "rpgatlas 1\n# c\ncolumns: 0\n", "atlas:3:10: columns must be above 0"},
+		{"tile size", "rpgatlas 1\ntile_size: big\n", `atlas:2:12: bad tile_size "big"`},
+		{"no colon", "rpgatlas 1\n[floor]\nglyph .\n", `atlas:3: expected "key: value"`},
+		{"no name", "rpgatlas 1\n[ ]\n", "atlas:2: sprite has no name"},
+		{"twice", "rpgatlas 1\n[floor]\ntile: 1,1\n[floor]\n", `atlas:4: sprite "floor" is defined twice`},
+		{"glyph", "rpgatlas 1\n[floor]\nglyph: ..\n", `atlas:3:8: glyph must be a single character, got ".."`},
+		{"indented", "rpgatlas 1\n[floor]\n  glyph :  ..\n", `atlas:3:12: glyph must be a single character, got ".."`},
+		{"tile", "rpgatlas 1\n[floor]\ntile: 1\n", `atlas:3:7: bad tile "1", expected x,y`},
+		{"rect", "rpgatlas 1\n[floor]\nrect: 1,2,0,4\n", `atlas:3:7: bad rect "1,2,0,4", expected x,y,w,h`},
+		{"frame", "rpgatlas 1\n[torch]\nframes: 1,1 100; 2 100\n", `atlas:3:9: bad frame "2", expected x,y or x,y,w,h`},
+		{"duration", "rpgatlas 1\n[torch]\nframes: 1,1 soon\n", `atlas:3:9: bad frame duration "soon"`},
+		{"weight", "rpgatlas 1\n[floor]\nweight:\t0\n", "atlas:3:9: weight must be above 0"},
+		{"unknown key", "rpgatlas 1\n[floor]\ncolour: red\n", `atlas:3: unknown key "colour"`},
+		{"both", "rpgatlas 1\n[floor]\ntile: 1,1\nrect: 0,0,8,8\n", `atlas:2: sprite "floor" has both a tile and a rect or frames`},
+		{"nothing", "rpgatlas 1\n[floor]\nglyph: .\n", `atlas:2: sprite "floor" needs a tile, rect or frames`},
+		{"variations", "rpgatlas 1\n[floor]\nrect: 0,0,8,8\nvariations: 2\n", `atlas:2: sprite "floor": variations only go with tile`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseAtlas(strings.NewReader(test.text))
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestStockAtlas(t *testing.T) {
+	file, err := Files.Open("assets/atlas-index.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	atlas, err := ParseAtlas(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, glyph := range []game.Title{'#', '.', '|', '/', '<', '>', ',', '~', '@', '!', ')', '['} {
+		if _, ok := atlas.First(glyph); !ok {
+			t.Errorf("no sprite for %q", glyph)
+		}
+	}
+	if n := len(atlas.Glyphs['.']); n != 7 {
+		t.Errorf("%d floor variations, want 7", n)
+	}
+
+	// every monster of the stock bestiary gets a sprite
+	bestiary, err := game.StockBestiary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	atlas.AddMonsters(bestiary.Defs())
+	for _, def := range bestiary.Defs() {
+		if _, ok := atlas.First(game.Title(def.Glyph)); !ok {
+			t.Errorf("no sprite for the %s", def.Name)
+		}
+	}
+}
+
+func TestParseAtlas(t *testing.T) {
+	atlas, err := ParseAtlas(strings.NewReader(`rpgatlas 1
+tile_size: 16x8
+columns: 4
+
+[floor]
+glyph: .
+tile: 2,1
+variations: 3
+
+[cracked floor]
+glyph: .
+rect: 100,50,16,16
+weight: 4
+
+[torch]
+frames: 1,0 100; 2,0,8,8 50ms
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var floors = atlas.Glyphs['.']
+	if len(floors) != 4 {
+		t.Fatalf("%d floor variations, want 4", len(floors))
+	}
+	// variations wrap at the column count
+	for i, want := range []sdl.Rect{{32, 8, 16, 8}, {48, 8, 16, 8}, {0, 16, 16, 8}, {100, 50, 16, 16}} {
+		if got := floors[i].Frames[0].Src; got != want {
+			t.Errorf("floor %d at %v, want %v", i, got, want)
+		}
+	}
+	if atlas.Sprites["floor"] != floors[0] || floors[3].Weight != 4 {
+		t.Errorf("named floor %p, cracked weight %d", atlas.Sprites["floor"], floors[3].Weight)
+	}
+	var torch = atlas.Sprites["torch"]
+	if torch == nil || torch.Glyph != 0 || len(torch.Frames) != 2 ||
+		torch.Frames[0] != (Frame{sdl.Rect{16, 0, 16, 8}, 100}) || torch.Frames[1] != (Frame{sdl.Rect{2, 0, 8, 8}, 50}) {
+		t.Errorf("torch %+v", torch)
+	}
+}
+
+func TestParseOldAtlas(t *testing.T) {
+	atlas, err := ParseAtlas(strings.NewReader("# 10,18,2\n\n. 62,7,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walls := atlas.Glyphs['#']; len(walls) != 2 || walls[1].Frames[0].Src != (sdl.Rect{352, 576, 32, 32}) {
+		t.Errorf("walls %+v", walls)
+	}
+	if floors := atlas.Glyphs['.']; len(floors) != 2 || floors[1].Frames[0].Src != (sdl.Rect{0, 256, 32, 32}) {
+		t.Errorf("floors don't wrap: %+v", floors)
+	}
+}
+
+func TestPick(t *testing.T) {
+	var atlas = newAtlas()
+	atlas.addRun("rare", '.', 0, 0, 1, 1)
+	atlas.addRun("common", '.', 1, 0, 1, 3)
+	var (
+		r      = rand.New(rand.NewSource(1))
+		picked = make(map[string]int)
+	)
+	for i := 0; i < 4000; i++ {
+		s, ok := atlas.Pick('.', r)
+		if !ok {
+			t.Fatal("nothing picked")
+		}
+		picked[s.Name]++
+	}
+	if picked["rare"] < 800 || picked["rare"] > 1200 || picked["rare"]+picked["common"] != 4000 {
+		t.Errorf("picked %v, want about 1000 rare", picked)
+	}
+	if _, ok := atlas.Pick('#', r); ok {
+		t.Error("picked a glyph without sprites")
+	}
+}
+
+func TestSpriteAt(t *testing.T) {
+	var (
+		a, b, c = sdl.Rect{X: 1}, sdl.Rect{X: 2}, sdl.Rect{X: 3}
+		torch   = Sprite{Frames: []Frame{{a, 100}, {b, 100}, {c, 200}}}
+		still   = Sprite{Frames: []Frame{{a, 0}}}
+	)
+	for ms, want := range map[uint32]sdl.Rect{0: a, 99: a, 100: b, 200: c, 399: c, 400: a, 950: b} {
+		if got := torch.At(ms); got != want {
+			t.Errorf("at %dms %v, want %v", ms, got, want)
+		}
+	}
+	if still.At(12345) != a {
+		t.Error("a still moved")
+	}
+}
+
+func TestAddMonsters(t *testing.T) {
+	var atlas = newAtlas()
+	atlas.addRun("Rat", 0, 5, 5, 1, 1)
+	atlas.addRun("spider", 'S', 6, 6, 1, 1)
+	atlas.AddMonsters([]*game.MonsterDef{
+		{Name: "Rat", Glyph: 'R', Sprite: game.Sprite{X: 1, Y: 1, Variations: 1}},
+		{Name: "Spider", Glyph: 'S', Sprite: game.Sprite{X: 2, Y: 2, Variations: 1}},
+		{Name: "Bat", Glyph: 'B', Sprite: game.Sprite{X: 3, Y: 3, Variations: 2}},
+		{Name: "Ghost", Glyph: 'G'},
+	})
+	// the sprite named after the monster beats the bestiary's
+	if rat, ok := atlas.First('R'); !ok || rat.Frames[0].Src != atlas.tile(5, 5) || atlas.Sprites["Rat"].Glyph != 0 {
+		t.Errorf("rat %+v", rat)
+	}
+	// a glyph with sprites keeps them
+	if spiders := atlas.Glyphs['S']; len(spiders) != 1 || spiders[0].Name != "spider" {
+		t.Errorf("spiders %+v", spiders)
+	}
+	if bats := atlas.Glyphs['B']; len(bats) != 2 || bats[1].Frames[0].Src != atlas.tile(4, 3) {
+		t.Errorf("bats %+v", bats)
+	}
+	if _, ok := atlas.First('G'); ok {
+		t.Error("a ghost without a sprite got one")
+	}
+}
@@ -0,0 +1,327 @@
+package ui2d
+
+import (
+	"experiments/experiments/RPG/game"
+	"experiments/experiments/assets"
+	"github.com/veandco/go-sdl2/sdl"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Action is what a key or controller button means to the ui, before Run
+// turns it into a game.Input.
+type Action int
+
+const (
+	NoAction Action = iota
+	ActUp
+	ActDown
+	ActLeft
+	ActRight
+	ActUpLeft
+	ActUpRight
+	ActDownLeft
+	ActDownRight
+	ActWait
+	ActExplore
+	ActPickup
+	ActDrop
+	ActSheet
+	ActRestart
+	ActQuit
+	ActMinimap
+	ActUse1
+	ActUse2
+	ActUse3
+	ActUse4
+	ActUse5
+	ActUse6
+	ActUse7
+	ActUse8
+	ActUse9
+	lastAction = ActUse9
+)
+
+var actionNames = map[string]Action{
+	"up":         ActUp,
+	"down":       ActDown,
+	"left":       ActLeft,
+	"right":      ActRight,
+	"up_left":    ActUpLeft,
+	"up_right":   ActUpRight,
+	"down_left":  ActDownLeft,
+	"down_right": ActDownRight,
+	"wait":       ActWait,
+	"explore":    ActExplore,
+	"pickup":     ActPickup,
+	"drop":       ActDrop,
+	"sheet":      ActSheet,
+	"restart":    ActRestart,
+	"quit":       ActQuit,
+	"minimap":    ActMinimap,
+	"use1":       ActUse1,
+	"use2":       ActUse2,
+	"use3":       ActUse3,
+	"use4":       ActUse4,
+	"use5":       ActUse5,
+	"use6":       ActUse6,
+	"use7":       ActUse7,
+	"use8":       ActUse8,
+	"use9":       ActUse9,
+}
+
+// repeats is true for the actions that fire again while held.
+func (a Action) repeats() bool {
+	return a >= ActUp && a <= ActWait
+}
+
+// Bindings map keys and controller buttons to actions.
+type Bindings struct {
+	Keys    map[sdl.Scancode]Action
+	Buttons map[sdl.GameControllerButton]Action
+	// a held movement key fires again after RepeatDelay and then every
+	// RepeatInterval, in milliseconds
+	RepeatDelay, RepeatInterval uint32
+}
+
+// LoadBindings reads a bindings file. A loader that mounts Files falls
+// back on the stock assets/bindings.txt.
+func LoadBindings(loader *assets.Loader, name string) (*Bindings, error) {
+	file, err := loader.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	bindings, err := ParseBindings(file)
+	if err != nil {
+		if bindingsErr, ok := err.(*game.MapError); ok {
+			bindingsErr.File = name
+		}
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// ParseBindings reads "action: key, key, pad:button" lines. A key or
+// button can only mean one thing. Errors are *game.MapError with File set
+// to "bindings".
+func ParseBindings(r io.Reader) (*Bindings, error) {
+	var (
+		bindings = &Bindings{
+			Keys:           make(map[sdl.Scancode]Action),
+			Buttons:        make(map[sdl.GameControllerButton]Action),
+			RepeatDelay:    250,
+			RepeatInterval: 80,
+		}
+		lines = game.NewLineScanner(r, "bindings")
+	)
+	for lines.Scan() {
+		if lines.Blank() {
+			continue
+		}
+		name, value, column, err := lines.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "repeat_delay", "repeat_interval":
+			ms, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, lines.Errorf(column, "bad %s %q", name, value)
+			}
+			if name == "repeat_delay" {
+				bindings.RepeatDelay = uint32(ms)
+			} else {
+				bindings.RepeatInterval = uint32(ms)
+			}
+			continue
+		}
+
+		action, ok := actionNames[name]
+		if !ok {
+			return nil, lines.Errorf(0, "unknown action %q", name)
+		}
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key == "" {
+				continue
+			}
+			if strings.HasPrefix(key, "pad:") {
+				var button = sdl.GameControllerGetButtonFromString(strings.TrimPrefix(key, "pad:"))
+				if button == sdl.CONTROLLER_BUTTON_INVALID {
+					return nil, lines.Errorf(0, "unknown controller button %q", key)
+				}
+				if old, taken := bindings.Buttons[button]; taken {
+					return nil, lines.Errorf(0, "%s is already bound to %s", key, old)
+				}
+				bindings.Buttons[button] = action
+				continue
+			}
+			if key == "Comma" {
+				// "," separates the keys
+				key = ","
+			}
+			var code = sdl.GetScancodeFromName(key)
+			if code == sdl.SCANCODE_UNKNOWN {
+				return nil, lines.Errorf(0, "unknown key %q", key)
+			}
+			if old, taken := bindings.Keys[code]; taken {
+				return nil, lines.Errorf(0, "%s is already bound to %s", key, old)
+			}
+			bindings.Keys[code] = action
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+func (a Action) String() string {
+	for name, action := range actionNames {
+		if action == a {
+			return name
+		}
+	}
+	return "none"
+}
+
+// stickDeadZone is how far the left stick has to be pushed to count as
+// a direction
+const stickDeadZone = 16000
+
+// heldActions are the actions whose keys or buttons are down right now.
+func (ui *ui) heldActions() map[Action]bool {
+	var held = make(map[Action]bool)
+	for code, action := range ui.bindings.Keys {
+		if ui.keyboardState[code] == 1 {
+			held[action] = true
+		}
+	}
+	for _, controller := range ui.controllers {
+		for button, action := range ui.bindings.Buttons {
+			if controller.Button(button) == 1 {
+				held[action] = true
+			}
+		}
+		if action := stickAction(controller.Axis(sdl.CONTROLLER_AXIS_LEFTX), controller.Axis(sdl.CONTROLLER_AXIS_LEFTY)); action != NoAction {
+			held[action] = true
+		}
+	}
+	return held
+}
+
+func stickAction(x, y int16) Action {
+	var dx, dy = 0, 0
+	switch {
+	case x < -stickDeadZone:
+		dx = -1
+	case x > stickDeadZone:
+		dx = 1
+	}
+	switch {
+	case y < -stickDeadZone:
+		dy = -1
+	case y > stickDeadZone:
+		dy = 1
+	}
+	switch {
+	case dx == 0 && dy == -1:
+		return ActUp
+	case dx == 0 && dy == 1:
+		return ActDown
+	case dx == -1 && dy == 0:
+		return ActLeft
+	case dx == 1 && dy == 0:
+		return ActRight
+	case dx == -1 && dy == -1:
+		return ActUpLeft
+	case dx == 1 && dy == -1:
+		return ActUpRight
+	case dx == -1 && dy == 1:
+		return ActDownLeft
+	case dx == 1 && dy == 1:
+		return ActDownRight
+	}
+	return NoAction
+}
+
+// pollActions returns the actions to carry out this frame: those just
+// pressed, and held movement once it starts repeating.
+func (ui *ui) pollActions() []Action {
+	var (
+		now     = sdl.GetTicks()
+		held    = ui.heldActions()
+		actions []Action
+	)
+	for action := ActUp; action <= lastAction; action++ {
+		if !held[action] {
+			delete(ui.repeatAt, action)
+			continue
+		}
+		next, wasHeld := ui.repeatAt[action]
+		switch {
+		case !wasHeld:
+			ui.repeatAt[action] = now + ui.bindings.RepeatDelay
+		case action.repeats() && now >= next:
+			ui.repeatAt[action] = now + ui.bindings.RepeatInterval
+		default:
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// openControllers (re)opens every attached game controller.
+func (ui *ui) openControllers() {
+	for _, controller := range ui.controllers {
+		controller.Close()
+	}
+	ui.controllers = ui.controllers[:0]
+	for i := 0; i < sdl.NumJoysticks(); i++ {
+		if !sdl.IsGameController(i) {
+			continue
+		}
+		if controller := sdl.GameControllerOpen(i); controller != nil {
+			ui.controllers = append(ui.controllers, controller)
+		}
+	}
+}
+
+var actionInputs = map[Action]game.InputType{
+	ActUp:        game.Up,
+	ActDown:      game.Down,
+	ActLeft:      game.Left,
+	ActRight:     game.Right,
+	ActUpLeft:    game.UpLeft,
+	ActUpRight:   game.UpRight,
+	ActDownLeft:  game.DownLeft,
+	ActDownRight: game.DownRight,
+	ActWait:      game.None,
+	ActExplore:   game.Explore,
+	ActPickup:    game.Pickup,
+}
+
+// gameInput is the game.Input an action stands for. ok is false for the
+// actions the ui handles itself and for those that only work on the death
+// screen.
+func (ui *ui) gameInput(action Action) (input game.Input, ok bool) {
+	if t, ok := actionInputs[action]; ok {
+		return game.Input{Type: t}, true
+	}
+	switch {
+	case action == ActDrop:
+		// drops the last item picked up
+		input = game.Input{Type: game.Drop, Item: -1}
+		if ui.drawn != nil {
+			input.Item = len(ui.drawn.Player.Inventory) - 1
+		}
+		return input, true
+	case action >= ActUse1 && action <= ActUse9:
+		// the item in that inventory slot
+		return game.Input{Type: game.Use, Item: int(action - ActUse1)}, true
+	}
+	return input, false
+}
@@ -0,0 +1,56 @@
+package ui2d
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"strings"
+	"testing"
+)
+
+func TestStockBindings(t *testing.T) {
+	file, err := Files.Open("assets/bindings.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	bindings, err := ParseBindings(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bindings.Keys[sdl.GetScancodeFromName("K")] != ActUp || bindings.Keys[sdl.GetScancodeFromName(",")] != ActPickup {
+		t.Error("stock keys are not bound")
+	}
+	if bindings.Buttons[sdl.GameControllerGetButtonFromString("start")] != ActRestart {
+		t.Error("stock buttons are not bound")
+	}
+}
+
+func TestParseBindings(t *testing.T) {
+	var tests = []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"ok", "# comment\nrepeat_delay: 100\n\nup: K, pad:dpup\npickup: Comma\n", ""},
+		{"no colon", "up K\n", `bindings:1: expected "key: value"`},
+		{"action", "\njump: Space\n", `bindings:2: unknown action "jump"`},
+		{"key", "up: Bogus\n", `bindings:1: unknown key "Bogus"`},
+		{"button", "up: pad:bogus\n", `bindings:1: unknown controller button "pad:bogus"`},
+		{"key twice", "up: K\ndown: K\n", "bindings:2: K is already bound to up"},
+		{"repeat", "# comment\nrepeat_interval:  soon\n", `bindings:2:19: bad repeat_interval "soon"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bindings, err := ParseBindings(strings.NewReader(test.text))
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatal(err)
+			case test.err == "":
+				if bindings.RepeatDelay != 100 || bindings.RepeatInterval != 80 || len(bindings.Keys) != 2 || len(bindings.Buttons) != 1 {
+					t.Errorf("parsed %+v", bindings)
+				}
+			case err == nil || err.Error() != test.err:
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
@@ -1,88 +1,156 @@
 package ui2d
 
 import (
-	"bufio"
+	"embed"
+	"errors"
 	"experiments/experiments/RPG/game"
+	"experiments/experiments/assets"
 	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 	"image/png"
-	"log"
+	"math"
 	"math/rand"
-	"os"
-	"strconv"
-	"strings"
 )
 
+// The ui's assets, named relative to the RPG directory.
+const (
+	AtlasImage   = "ui2d/assets/tiles.png"
+	AtlasIndex   = "ui2d/assets/atlas-index.txt"
+	FontFile     = "ui2d/assets/Kingthings_Foundation.ttf"
+	BindingsFile = "ui2d/assets/bindings.txt"
+)
+
+// Files are the ui's assets that are compiled in, relative to this
+// package's directory. The tile atlas, tiles.png, isn't in the
+// repository and has to be found on disk.
+//
+//go:embed assets/atlas-index.txt assets/bindings.txt assets/Kingthings_Foundation.ttf
+var Files embed.FS
+
+var errNoPlayerSprite = errors.New("ui2d: the atlas has no player sprite")
+
 type ui struct {
-	windowWidth       int32
-	windowHeight      int32
-	renderer          *sdl.Renderer
-	window            *sdl.Window
-	textureAtlas      *sdl.Texture
-	textureIndex      map[game.Title][]sdl.Rect
-	keyboardState     []uint8
-	prevKeyboardState []uint8
-	centerX           int
-	centerY           int
-	r                 *rand.Rand
-	levelChan         chan *game.Level
-	inputChan         chan *game.Input
-	fontSmall         *ttf.Font
-	fontMedium        *ttf.Font
-	fontLarge         *ttf.Font
+	windowWidth      int32
+	windowHeight     int32
+	renderer         *sdl.Renderer
+	window           *sdl.Window
+	id               uint32 // the window's, to tell its events apart
+	textureAtlas     *sdl.Texture
+	atlas            *Atlas
+	keyboardState    []uint8
+	bindings         *Bindings
+	controllers      []*sdl.GameController
+	repeatAt         map[Action]uint32 // when a held action fires next
+	centerX          int
+	centerY          int
+	camera           vec // eases towards centerX, centerY
+	cameraSet        bool
+	clock            frameClock
+	actors           map[int]*actorAnim // by actor ID or playerID
+	offsetX, offsetY int32              // where tile 0,0 was drawn, for mouse clicks
+	drawn            *game.Snapshot
+	showSheet        bool
+	r                *rand.Rand
+	levelChan        chan *game.Snapshot
+	inputChan        chan *game.Input
+	fontSmall        *ttf.Font
+	fontMedium       *ttf.Font
+	fontLarge        *ttf.Font
+	fontData         []byte // kept for as long as the fonts use it
+	assets           *assets.Loader
+	bestiary         game.Bestiary // monsters whose sprites the atlas lacks
+	follow           int           // ID of the monster a spectator watches
+	views            []view        // the other windows Run drives
 
 	stringTextureSmall  map[string]*sdl.Texture
 	stringTextureMedium map[string]*sdl.Texture
 	stringTextureLarge  map[string]*sdl.Texture
 }
 
-func NewUI(inputChan chan *game.Input, levelChan chan *game.Level) *ui {
+// NewUI opens a window on the level snapshots sent down levelChan. Its
+// art, font and key bindings come from loader, the sprites of monsters
+// the atlas doesn't know from bestiary.
+func NewUI(inputChan chan *game.Input, levelChan chan *game.Snapshot, loader *assets.Loader, bestiary game.Bestiary) (*ui, error) {
+	newUI, err := newWindow("RPG", 1280, 720, levelChan, loader, bestiary)
+	if err != nil {
+		return nil, err
+	}
+	newUI.inputChan = inputChan
+	newUI.keyboardState = sdl.GetKeyboardState()
+	if newUI.bindings, err = LoadBindings(loader, BindingsFile); err != nil {
+		newUI.destroy()
+		return nil, err
+	}
+	newUI.repeatAt = make(map[Action]uint32)
+	newUI.openControllers()
+	return newUI, nil
+}
+
+// newWindow opens a window that can draw levels, without any input; NewUI
+// and spectators build on it. If the assets fail to load the window is
+// closed again.
+func newWindow(title string, width, height int32, levelChan chan *game.Snapshot, loader *assets.Loader, bestiary game.Bestiary) (newUI *ui, err error) {
 
-	var newUI = &ui{
-		inputChan:           inputChan,
+	newUI = &ui{
+		assets:              loader,
+		bestiary:            bestiary,
 		stringTextureSmall:  make(map[string]*sdl.Texture),
 		stringTextureMedium: make(map[string]*sdl.Texture),
 		stringTextureLarge:  make(map[string]*sdl.Texture),
 		levelChan:           levelChan,
-		windowWidth:         1280,
-		windowHeight:        720,
+		windowWidth:         width,
+		windowHeight:        height,
 		r:                   rand.New(rand.NewSource(1)),
 	}
 
-	window, err := sdl.CreateWindow("RPG", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, newUI.windowWidth, newUI.windowHeight,
-		sdl.WINDOW_SHOWN)
-	if err != nil {
-		panic(err)
+	if newUI.window, err = sdl.CreateWindow(title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, newUI.windowWidth, newUI.windowHeight,
+		sdl.WINDOW_SHOWN); err != nil {
+		return nil, err
 	}
-	newUI.window = window
-	renderer, err := sdl.CreateRenderer(newUI.window, -1, sdl.RENDERER_ACCELERATED)
-	if err != nil {
-		panic(err)
+	if newUI.renderer, err = sdl.CreateRenderer(newUI.window, -1, sdl.RENDERER_ACCELERATED); err != nil {
+		newUI.window.Destroy()
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			newUI.destroy()
+			newUI = nil
+		}
+	}()
+	if newUI.id, err = newUI.window.GetID(); err != nil {
+		return newUI, err
 	}
-	newUI.renderer = renderer
 	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "1")
 
-	newUI.textureAtlas = newUI.imgFileToTexture("C:/Users/xpoc_/go/src/experiments/experiments/RPG/ui2d/assets/tiles.png")
-	newUI.loadTextureIndex("C:/Users/xpoc_/go/src/experiments/experiments/RPG/ui2d/assets/atlas-index.txt")
-	newUI.keyboardState = sdl.GetKeyboardState()
-	newUI.prevKeyboardState = make([]uint8, len(newUI.keyboardState))
-	for i, v := range newUI.keyboardState {
-		newUI.prevKeyboardState[i] = v
+	if newUI.textureAtlas, err = newUI.imgFileToTexture(AtlasImage); err != nil {
+		return newUI, err
 	}
-	newUI.centerX = -1
-	newUI.centerY = -1
-
-	if newUI.fontSmall, err = ttf.OpenFont("C:/Users/xpoc_/go/src/experiments/experiments/RPG/ui2d/assets/Kingthings_Foundation.ttf", 16); err != nil {
-		panic(err)
+	if err = newUI.loadAtlas(AtlasIndex); err != nil {
+		return newUI, err
 	}
-	if newUI.fontMedium, err = ttf.OpenFont("C:/Users/xpoc_/go/src/experiments/experiments/RPG/ui2d/assets/Kingthings_Foundation.ttf", 32); err != nil {
-		panic(err)
+	if newUI.fontData, err = loader.ReadFile(FontFile); err != nil {
+		return newUI, err
 	}
-	if newUI.fontLarge, err = ttf.OpenFont("C:/Users/xpoc_/go/src/experiments/experiments/RPG/ui2d/assets/Kingthings_Foundation.ttf", 64); err != nil {
-		panic(err)
+	if newUI.fontSmall, err = newUI.openFont(16); err != nil {
+		return newUI, err
+	}
+	if newUI.fontMedium, err = newUI.openFont(32); err != nil {
+		return newUI, err
 	}
-	return newUI
+	if newUI.fontLarge, err = newUI.openFont(64); err != nil {
+		return newUI, err
+	}
+	return newUI, nil
+}
+
+// openFont reads the font from memory since it may be embedded.
+func (ui *ui) openFont(size int) (*ttf.Font, error) {
+	rw, err := sdl.RWFromMem(ui.fontData)
+	if err != nil {
+		return nil, err
+	}
+	return ttf.OpenFontRW(rw, 1, size)
 }
 
 type FountSize int
@@ -145,61 +213,39 @@ func (ui *ui) stringToTexture(str string, color sdl.Color, size FountSize) *sdl.
 type UI2d struct {
 }
 
-func (ui *ui) loadTextureIndex(fileName string) {
-	ui.textureIndex = make(map[game.Title][]sdl.Rect)
-	// C:\Users\xpoc_\go\src\experiments\experiments\RPG\ui2d\assets
-	file, err := os.Open(fileName)
+func (ui *ui) loadAtlas(fileName string) error {
+	file, err := ui.assets.Open(fileName)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer file.Close()
 
-	var scanner = bufio.NewScanner(file)
-	for scanner.Scan() {
-		var (
-			line     = strings.TrimSpace(scanner.Text())
-			tileRune = game.Title(line[0])
-			xy       = line[1:]
-			splitXYC = strings.Split(xy, ",")
-		)
-		x, err := strconv.ParseInt(strings.TrimSpace(splitXYC[0]), 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		y, err := strconv.ParseInt(strings.TrimSpace(splitXYC[1]), 10, 64)
-		if err != nil {
-			panic(err)
-		}
-
-		variationCount, err := strconv.ParseInt(strings.TrimSpace(splitXYC[2]), 10, 64)
-		if err != nil {
-			panic(err)
-		}
-
-		var rects []sdl.Rect
-		for i := int64(0); i < variationCount; i++ {
-			rects = append(rects, sdl.Rect{int32(x * 32), int32(y * 32), 32, 32})
-			x++
-			if x > 62 {
-				x = 0
-				y++
-			}
+	atlas, err := ParseAtlas(file)
+	if err != nil {
+		if atlasErr, ok := err.(*game.MapError); ok {
+			atlasErr.File = fileName
 		}
-		ui.textureIndex[tileRune] = rects
+		return err
 	}
-
+	// the bestiary knows where its monsters are in the atlas
+	atlas.AddMonsters(ui.bestiary.Defs())
+	if _, ok := atlas.First('@'); !ok {
+		return errNoPlayerSprite
+	}
+	ui.atlas = atlas
+	return nil
 }
 
-func (ui *ui) imgFileToTexture(fileName string) *sdl.Texture {
-	file, err := os.Open(fileName)
+func (ui *ui) imgFileToTexture(fileName string) (*sdl.Texture, error) {
+	file, err := ui.assets.Open(fileName)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer file.Close()
 
 	img, err := png.Decode(file)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("ui2d: %s: %w", fileName, err)
 	}
 
 	w := img.Bounds().Max.X
@@ -223,10 +269,9 @@ func (ui *ui) imgFileToTexture(fileName string) *sdl.Texture {
 
 	tex := pixelsToTexture(ui.renderer, pixels, w, h)
 	if err := tex.SetBlendMode(sdl.BLENDMODE_BLEND); err != nil {
-		log.Fatal(err)
-		return nil
+		return nil, err
 	}
-	return tex
+	return tex, nil
 }
 
 func pixelsToTexture(renderer *sdl.Renderer, pixels []byte, w, h int) *sdl.Texture {
@@ -250,118 +295,313 @@ func init() {
 	}
 }
 
-func (ui *ui) Draw(level *game.Level) {
-	if ui.centerX == -1 && ui.centerY == -1 {
-		ui.centerY = level.Player.Y
-		ui.centerX = level.Player.X
-	}
-	// центрирование камеры
-	var limit = 5
-	if level.Player.X > ui.centerX+limit {
-		ui.centerX++
-	} else if level.Player.X < ui.centerX-limit {
-		ui.centerX--
-	} else if level.Player.Y > ui.centerY+limit {
-		ui.centerY++
-	} else if level.Player.Y < ui.centerY-limit {
-		ui.centerY--
-	}
+// Draw draws level, and returns why if a sprite couldn't be.
+func (ui *ui) Draw(level *game.Snapshot) error {
+	ui.updateCamera(ui.cameraTarget(level))
 	var (
-		offsetX = ui.windowWidth/2 - int32(ui.centerX*32)
-		offsetY = ui.windowHeight/2 - int32(ui.centerY*32)
+		offsetX = ui.windowWidth/2 - int32(math.Round(ui.camera.x*32))
+		offsetY = ui.windowHeight/2 - int32(math.Round(ui.camera.y*32))
 	)
+	ui.offsetX, ui.offsetY = offsetX, offsetY
 	ui.renderer.Clear()
 	ui.r.Seed(1)
-	for y, row := range level.Map {
-		for x, tile := range row {
-			if tile == game.Blank {
+	var now = ui.clock.now
+	for y := 0; y < level.Height(); y++ {
+		for x := 0; x < level.Width(); x++ {
+			var tile = level.Tile(game.Position{x, y})
+			if tile.Title == game.Blank {
 				continue
 			}
 			var (
-				scrRects = ui.textureIndex[tile]
-				scrRect  = scrRects[ui.r.Intn(len(scrRects))]
-				dstRect  = sdl.Rect{int32(x*32) + offsetX, int32(y*32) + offsetY, 32, 32}
+				sprite, ok = ui.atlas.Pick(tile.Title, ui.r)
+				dstRect    = sdl.Rect{int32(x*32) + offsetX, int32(y*32) + offsetY, 32, 32}
 			)
-			if level.Debug[game.Position{x, y}] {
+			// variations are picked before the fog check so that
+			// exploring doesn't reshuffle the tiles already drawn
+			if !ok || !ui.explored(tile) {
+				continue
+			}
+			var scrRect = sprite.At(now)
+			if tile.Debug {
 				ui.textureAtlas.SetColorMod(128, 0, 0)
+			} else if !ui.visible(tile) {
+				ui.textureAtlas.SetColorMod(96, 96, 96)
 			} else {
 				ui.textureAtlas.SetColorMod(255, 255, 255)
 			}
 			ui.renderer.Copy(ui.textureAtlas, &scrRect, &dstRect)
 		}
 	}
+	ui.textureAtlas.SetColorMod(255, 255, 255)
+
+	for pos, items := range level.Items {
+		if !ui.explored(level.Tile(pos)) || len(items) == 0 {
+			continue
+		}
+		if sprite, ok := ui.atlas.First(game.Title(items[len(items)-1].Rune)); ok {
+			if !ui.visible(level.Tile(pos)) {
+				ui.textureAtlas.SetColorMod(96, 96, 96)
+			}
+			var srcRect = sprite.At(now)
+			ui.renderer.Copy(ui.textureAtlas, &srcRect, &sdl.Rect{int32(pos.X)*32 + offsetX, int32(pos.Y)*32 + offsetY, 32, 32})
+			ui.textureAtlas.SetColorMod(255, 255, 255)
+		}
+	}
 
 	for pos, monster := range level.Monsters {
-		monsterSrcRect := ui.textureIndex[game.Title(monster.Rune)][0]
-		ui.renderer.Copy(ui.textureAtlas, &monsterSrcRect, &sdl.Rect{int32(pos.X)*32 + offsetX, int32(pos.Y)*32 + offsetY, 32, 32})
+		if !ui.visible(level.Tile(pos)) {
+			continue
+		}
+		if sprite, ok := ui.atlas.First(game.Title(monster.Rune)); ok {
+			if err := ui.drawActor(monster.ID, pos, sprite, offsetX, offsetY); err != nil {
+				return err
+			}
+		}
 	}
-	playerSrcRect := ui.textureIndex['@'][0]
-	if err := ui.renderer.Copy(ui.textureAtlas, &playerSrcRect, &sdl.Rect{int32(level.Player.X)*32 + offsetX, int32(level.Player.Y)*32 + offsetY, 32, 32}); err != nil {
-		panic(err)
+	player, ok := ui.atlas.First('@')
+	if !ok {
+		return errNoPlayerSprite
+	}
+	for pos, other := range level.Others {
+		// the rest of the party is tinted, they share what they see
+		ui.textureAtlas.SetColorMod(150, 190, 255)
+		if err := ui.drawActor(other.ID, pos, player, offsetX, offsetY); err != nil {
+			return err
+		}
+	}
+	ui.textureAtlas.SetColorMod(255, 255, 255)
+	if err := ui.drawActor(playerID, level.Player.Position, player, offsetX, offsetY); err != nil {
+		return err
 	}
 
 	textStart := int32(float64(ui.windowHeight) * .75)
-	for i, event := range level.Events {
-		if event != "" {
-			tex := ui.stringToTexture(event, sdl.Color{255, 0, 0, 0}, FontSmall)
-			if _, _, w, h, err := tex.Query(); err != nil {
-				panic(err)
-			} else {
-				ui.renderer.Copy(tex, nil, &sdl.Rect{0, int32(i*int(FontSmall)) + textStart, w, h})
-			}
+	for i, event := range level.Messages(10) {
+		tex := ui.stringToTexture(event, sdl.Color{255, 0, 0, 0}, FontSmall)
+		if _, _, w, h, err := tex.Query(); err != nil {
+			panic(err)
+		} else {
+			ui.renderer.Copy(tex, nil, &sdl.Rect{0, int32(i*int(FontSmall)) + textStart, w, h})
+		}
+	}
+	if ui.follow == 0 {
+		ui.drawInventory(&level.Player)
+		if ui.showSheet {
+			ui.drawCharacterSheet(&level.Player)
+		}
+		if level.Player.Hitpoints <= 0 {
+			ui.drawDeathScreen(level)
 		}
 	}
 
 	ui.renderer.Present()
+	return nil
+}
+
+func (ui *ui) drawDeathScreen(level *game.Snapshot) {
+	var cause = "Died"
+	if death, ok := level.PlayerDeath(); ok {
+		cause = fmt.Sprintf("Killed by %s on turn %d", death.Actor.Name, death.Turn)
+	}
+	ui.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	ui.renderer.SetDrawColor(64, 0, 0, 200)
+	ui.renderer.FillRect(&sdl.Rect{0, 0, ui.windowWidth, ui.windowHeight})
+	ui.renderer.SetDrawColor(0, 0, 0, 255)
+
+	var lines = []struct {
+		text string
+		size FountSize
+	}{
+		{"YOU DIED", FontLarge},
+		{cause, FontMedium},
+		{fmt.Sprintf("Level %d, %d XP", level.Player.Level, level.Player.Experience), FontMedium},
+		{"R - restart   Q - quit", FontSmall},
+	}
+	var y = ui.windowHeight / 3
+	for _, line := range lines {
+		tex := ui.stringToTexture(line.text, sdl.Color{255, 255, 255, 0}, line.size)
+		if _, _, w, h, err := tex.Query(); err != nil {
+			panic(err)
+		} else {
+			ui.renderer.Copy(tex, nil, &sdl.Rect{(ui.windowWidth - w) / 2, y, w, h})
+			y += h + 10
+		}
+	}
+}
+
+func (ui *ui) drawInventory(player *game.Player) {
+	var lines = []string{fmt.Sprintf("HP %d/%d", player.Hitpoints, player.MaxHitpoints)}
+	if player.Weapon != nil {
+		lines = append(lines, "Weapon: "+player.Weapon.Name)
+	}
+	if player.Armor != nil {
+		lines = append(lines, "Armor: "+player.Armor.Name)
+	}
+	for i, item := range player.Inventory {
+		lines = append(lines, fmt.Sprintf("%d %s", i+1, item.Name))
+	}
+
+	var left = ui.windowWidth - 200
+	for i, line := range lines {
+		tex := ui.stringToTexture(line, sdl.Color{255, 255, 255, 0}, FontSmall)
+		if _, _, w, h, err := tex.Query(); err != nil {
+			panic(err)
+		} else {
+			ui.renderer.Copy(tex, nil, &sdl.Rect{left, int32(i * int(FontSmall)), w, h})
+		}
+	}
+}
+
+func (ui *ui) screenToTile(x, y int32) game.Position {
+	var tileX, tileY = x - ui.offsetX, y - ui.offsetY
+	// round towards minus infinity, left of tile 0 is -1
+	if tileX < 0 {
+		tileX -= 31
+	}
+	if tileY < 0 {
+		tileY -= 31
+	}
+	return game.Position{int(tileX / 32), int(tileY / 32)}
+}
+
+// drawCharacterSheet is the C overlay with the player's stats and how
+// far they are from the next level.
+func (ui *ui) drawCharacterSheet(player *game.Player) {
+	var (
+		into, span = game.DefaultProgression.Progress(player.Experience)
+		lo, hi     = player.GetDamageRange()
+		progress   = "max level"
+	)
+	if span > 0 {
+		progress = fmt.Sprintf("%d/%d to level %d", into, span, player.Level+1)
+	}
+	var lines = []string{
+		player.Name,
+		fmt.Sprintf("Level %d", player.Level),
+		fmt.Sprintf("XP %d (%s)", player.Experience, progress),
+		fmt.Sprintf("HP %d/%d", player.Hitpoints, player.MaxHitpoints),
+		fmt.Sprintf("Strength %d", player.Strength),
+		fmt.Sprintf("Speed %.2f", player.Speed),
+		fmt.Sprintf("Damage %d-%d", lo, hi),
+		fmt.Sprintf("Defense %d", player.GetDefense()),
+		fmt.Sprintf("Accuracy %d  Evasion %d  Crit %d", player.Accuracy, player.Evasion, player.CritChance),
+	}
+
+	var (
+		lineHeight = int32(FontMedium)
+		panel      = sdl.Rect{ui.windowWidth/2 - 250, ui.windowHeight/2 - 200, 500, int32(len(lines))*lineHeight + 40}
+	)
+	ui.renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	ui.renderer.SetDrawColor(0, 0, 0, 200)
+	ui.renderer.FillRect(&panel)
+	ui.renderer.SetDrawColor(255, 255, 255, 255)
+	ui.renderer.DrawRect(&panel)
+	ui.renderer.SetDrawColor(0, 0, 0, 255)
+
+	// experience bar under the XP line
+	if span > 0 {
+		var bar = sdl.Rect{panel.X + 20, panel.Y + 20 + 3*lineHeight - 4, 460, 4}
+		ui.renderer.SetDrawColor(64, 64, 64, 255)
+		ui.renderer.FillRect(&bar)
+		bar.W = bar.W * int32(into) / int32(span)
+		ui.renderer.SetDrawColor(255, 215, 0, 255)
+		ui.renderer.FillRect(&bar)
+		ui.renderer.SetDrawColor(0, 0, 0, 255)
+	}
+
+	for i, line := range lines {
+		tex := ui.stringToTexture(line, sdl.Color{255, 255, 255, 0}, FontMedium)
+		if _, _, w, h, err := tex.Query(); err != nil {
+			panic(err)
+		} else {
+			ui.renderer.Copy(tex, nil, &sdl.Rect{panel.X + 20, panel.Y + 20 + int32(i)*lineHeight, w, h})
+		}
+	}
 }
 
-func (ui *ui) Run() {
+// Run plays until the player quits or closes the window. It returns why
+// if a window couldn't be opened or drawn, the game is quit then too.
+func (ui *ui) Run() error {
 	for {
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 			switch e := event.(type) {
 			case *sdl.QuitEvent:
-				fmt.Println("QuitGame")
-				ui.inputChan <- &game.Input{Type: game.QuitGame}
+				ui.inputChan <- &game.Input{Type: game.QuitGame, LevelChannel: ui.levelChan}
+				return nil
+			case *sdl.MouseButtonEvent:
+				if e.WindowID != ui.id || e.State != sdl.PRESSED || ui.drawn == nil ||
+					ui.drawn.Player.Hitpoints <= 0 {
+					break
+				}
+				switch e.Button {
+				case sdl.BUTTON_LEFT:
+					// click to travel
+					ui.inputChan <- &game.Input{Type: game.Travel, LevelChannel: ui.levelChan, Target: ui.screenToTile(e.X, e.Y)}
+				case sdl.BUTTON_RIGHT:
+					if err := ui.spectate(ui.screenToTile(e.X, e.Y)); err != nil {
+						ui.inputChan <- &game.Input{Type: game.QuitGame, LevelChannel: ui.levelChan}
+						return err
+					}
+				}
+			case *sdl.ControllerDeviceEvent:
+				if e.Type == sdl.CONTROLLERDEVICEADDED || e.Type == sdl.CONTROLLERDEVICEREMOVED {
+					ui.openControllers()
+				}
 			case *sdl.WindowEvent:
+				if v, ok := ui.viewByID(e.WindowID); ok {
+					if e.Event == sdl.WINDOWEVENT_CLOSE {
+						ui.closeView(v)
+					}
+					break
+				}
 				switch e.Event {
 				case sdl.WINDOWEVENT_CLOSE:
-					fmt.Println("CloseWindow")
 					ui.inputChan <- &game.Input{Type: game.CloseWindow, LevelChannel: ui.levelChan}
+					return nil
 				}
 			}
 		}
 
-		select {
-		case newLevel, ok := <-ui.levelChan:
-			if ok {
-				ui.Draw(newLevel)
+		var err = ui.frame()
+		for _, v := range ui.views {
+			if err == nil {
+				err = v.frame()
 			}
-		default:
+		}
+		if err != nil {
+			ui.inputChan <- &game.Input{Type: game.QuitGame, LevelChannel: ui.levelChan}
+			return err
 		}
 
-		if sdl.GetKeyboardFocus() == ui.window || sdl.GetMouseFocus() == ui.window {
-
-			var input game.Input
-			if ui.keyboardState[sdl.SCANCODE_UP] == 1 && ui.prevKeyboardState[sdl.SCANCODE_UP] == 0 {
-				input.Type = game.Up
-			}
-			if ui.keyboardState[sdl.SCANCODE_DOWN] == 1 && ui.prevKeyboardState[sdl.SCANCODE_DOWN] == 0 {
-				input.Type = game.Down
-			}
-			if ui.keyboardState[sdl.SCANCODE_LEFT] == 1 && ui.prevKeyboardState[sdl.SCANCODE_LEFT] == 0 {
-				input.Type = game.Left
-			}
-			if ui.keyboardState[sdl.SCANCODE_RIGHT] == 1 && ui.prevKeyboardState[sdl.SCANCODE_RIGHT] == 0 {
-				input.Type = game.Right
-			}
-			if ui.keyboardState[sdl.SCANCODE_S] == 1 && ui.prevKeyboardState[sdl.SCANCODE_S] == 0 {
-				input.Type = game.Search
+		if sdl.GetKeyboardFocus() != ui.window && sdl.GetMouseFocus() != ui.window {
+			sdl.Delay(10)
+			continue
+		}
+		for _, action := range ui.pollActions() {
+			if ui.drawn != nil && ui.drawn.Player.Hitpoints <= 0 {
+				// the death screen only restarts or quits
+				switch action {
+				case ActRestart:
+					ui.inputChan <- &game.Input{Type: game.Restart, LevelChannel: ui.levelChan}
+				case ActQuit:
+					ui.inputChan <- &game.Input{Type: game.QuitGame, LevelChannel: ui.levelChan}
+					return nil
+				}
+				continue
 			}
-			for i, v := range ui.keyboardState {
-				ui.prevKeyboardState[i] = v
+			switch action {
+			case ActSheet:
+				// not a game input, the next frame has or hasn't the sheet
+				ui.showSheet = !ui.showSheet
+				continue
+			case ActMinimap:
+				if err := ui.toggleMinimap(); err != nil {
+					ui.inputChan <- &game.Input{Type: game.QuitGame, LevelChannel: ui.levelChan}
+					return err
+				}
+				continue
 			}
-
-			if input.Type != game.None {
+			if input, ok := ui.gameInput(action); ok {
+				input.LevelChannel = ui.levelChan
 				ui.inputChan <- &input
 			}
 		}
@@ -0,0 +1,266 @@
+package ui2d
+
+import (
+	"experiments/experiments/RPG/game"
+	"experiments/experiments/assets"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// view is a window besides the main one, a spectator or the minimap.
+// SDL wants a single event loop, so the main ui's Run drives them all;
+// each has a level channel of its own that the game attaches with
+// OpenWindow and never waits on.
+type view interface {
+	windowID() uint32
+	levels() chan *game.Snapshot
+	// frame takes the latest snapshot if there's a new one and draws
+	frame() error
+	destroy()
+}
+
+func (ui *ui) openView(v view) {
+	ui.views = append(ui.views, v)
+	ui.inputChan <- &game.Input{Type: game.OpenWindow, LevelChannel: v.levels()}
+}
+
+func (ui *ui) closeView(v view) {
+	ui.inputChan <- &game.Input{Type: game.CloseWindow, LevelChannel: v.levels()}
+	v.destroy()
+	for i, other := range ui.views {
+		if other == v {
+			ui.views = append(ui.views[:i], ui.views[i+1:]...)
+			break
+		}
+	}
+}
+
+func (ui *ui) viewByID(id uint32) (view, bool) {
+	for _, v := range ui.views {
+		if v.windowID() == id {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (ui *ui) toggleMinimap() error {
+	for _, v := range ui.views {
+		if _, ok := v.(*minimap); ok {
+			ui.closeView(v)
+			return nil
+		}
+	}
+	m, err := newMinimap()
+	if err != nil {
+		return err
+	}
+	ui.openView(m)
+	return nil
+}
+
+// spectate opens a window following the monster or other player at pos,
+// if the player can see one there.
+func (ui *ui) spectate(pos game.Position) error {
+	var level = ui.drawn
+	if level == nil || !level.Tile(pos).Visible {
+		return nil
+	}
+	var actor, ok = level.Monsters[pos]
+	if !ok {
+		if actor, ok = level.Others[pos]; !ok {
+			return nil
+		}
+	}
+	spectator, err := newSpectator(actor, ui.assets, ui.bestiary)
+	if err != nil {
+		return err
+	}
+	ui.openView(spectator)
+	return nil
+}
+
+// newSpectator is a ui without input that follows m and sees the whole
+// level.
+func newSpectator(m game.Actor, loader *assets.Loader, bestiary game.Bestiary) (*ui, error) {
+	spectator, err := newWindow("Spectating "+m.Name, 640, 480, game.NewLevelChan(), loader, bestiary)
+	if err != nil {
+		return nil, err
+	}
+	spectator.follow = m.ID
+	return spectator, nil
+}
+
+func (ui *ui) windowID() uint32 {
+	return ui.id
+}
+
+func (ui *ui) levels() chan *game.Snapshot {
+	return ui.levelChan
+}
+
+func (ui *ui) frame() error {
+	ui.clock.tick()
+	select {
+	case level, ok := <-ui.levelChan:
+		if ok {
+			ui.observe(level)
+		}
+	default:
+	}
+	// redrawn every frame for the animations, whether or not a turn has
+	// passed
+	if ui.drawn != nil {
+		return ui.Draw(ui.drawn)
+	}
+	return nil
+}
+
+func (ui *ui) destroy() {
+	ui.renderer.Destroy()
+	ui.window.Destroy()
+}
+
+func (ui *ui) cameraTarget(level *game.Snapshot) game.Position {
+	if ui.follow == 0 {
+		return level.Player.Position
+	}
+	if m, ok := level.Actor(ui.follow); ok {
+		return m.Position
+	}
+	if ui.cameraSet {
+		// it died or the player left the level, stay where it was last
+		return game.Position{ui.centerX, ui.centerY}
+	}
+	return level.Player.Position
+}
+
+// explored and visible are what the window shows: the player's view, or
+// everything for a spectator.
+func (ui *ui) explored(tile game.Tile) bool {
+	return ui.follow != 0 || tile.Explored
+}
+
+func (ui *ui) visible(tile game.Tile) bool {
+	return ui.follow != 0 || tile.Visible
+}
+
+// minimap shows what the player has explored, a few pixels per tile.
+type minimap struct {
+	window    *sdl.Window
+	renderer  *sdl.Renderer
+	id        uint32
+	levelChan chan *game.Snapshot
+	level     *game.Snapshot
+	width     int32
+	height    int32
+}
+
+var minimapColors = map[game.Title]sdl.Color{
+	game.StoneWall: {140, 140, 140, 255},
+	game.DirtFloor: {50, 45, 40, 255},
+	game.CloseDoor: {150, 95, 40, 255},
+	game.OpenDoor:  {150, 95, 40, 255},
+	game.UpStair:   {240, 210, 60, 255},
+	game.DownStair: {240, 210, 60, 255},
+	game.Mud:       {95, 70, 40, 255},
+	game.Water:     {40, 80, 200, 255},
+}
+
+func newMinimap() (*minimap, error) {
+	var m = &minimap{levelChan: game.NewLevelChan(), width: 320, height: 240}
+	window, err := sdl.CreateWindow("Map", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, m.width, m.height, sdl.WINDOW_SHOWN)
+	if err != nil {
+		return nil, err
+	}
+	m.window = window
+	if m.id, err = window.GetID(); err != nil {
+		window.Destroy()
+		return nil, err
+	}
+	if m.renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED); err != nil {
+		window.Destroy()
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *minimap) windowID() uint32 {
+	return m.id
+}
+
+func (m *minimap) levels() chan *game.Snapshot {
+	return m.levelChan
+}
+
+func (m *minimap) destroy() {
+	m.renderer.Destroy()
+	m.window.Destroy()
+}
+
+func (m *minimap) frame() error {
+	m.update()
+	if m.level != nil {
+		m.draw(m.level)
+	}
+	return nil
+}
+
+// update takes the latest snapshot if there's a new one.
+func (m *minimap) update() {
+	select {
+	case level, ok := <-m.levelChan:
+		if ok {
+			m.level = level
+		}
+	default:
+	}
+}
+
+// layout is the side of a tile's cell and where the level's top left
+// corner goes: the largest square cells that fit, centered.
+func (m *minimap) layout(level *game.Snapshot) (cell, left, top int32) {
+	cell = m.width / int32(level.Width())
+	if h := m.height / int32(level.Height()); h < cell {
+		cell = h
+	}
+	if cell < 1 {
+		cell = 1
+	}
+	return cell, (m.width - cell*int32(level.Width())) / 2, (m.height - cell*int32(level.Height())) / 2
+}
+
+func (m *minimap) draw(level *game.Snapshot) {
+	m.renderer.SetDrawColor(0, 0, 0, 255)
+	m.renderer.Clear()
+	if level.Width() == 0 {
+		m.renderer.Present()
+		return
+	}
+	var cell, left, top = m.layout(level)
+	var fill = func(pos game.Position, c sdl.Color) {
+		m.renderer.SetDrawColor(c.R, c.G, c.B, c.A)
+		m.renderer.FillRect(&sdl.Rect{left + int32(pos.X)*cell, top + int32(pos.Y)*cell, cell, cell})
+	}
+
+	for y := 0; y < level.Height(); y++ {
+		for x := 0; x < level.Width(); x++ {
+			var (
+				pos  = game.Position{x, y}
+				tile = level.Tile(pos)
+			)
+			if c, ok := minimapColors[tile.Title]; ok && tile.Explored {
+				fill(pos, c)
+			}
+		}
+	}
+	for pos := range level.Monsters {
+		if level.Tile(pos).Visible {
+			fill(pos, sdl.Color{220, 40, 40, 255})
+		}
+	}
+	for pos := range level.Others {
+		fill(pos, sdl.Color{150, 190, 255, 255})
+	}
+	fill(level.Player.Position, sdl.Color{255, 255, 255, 255})
+	m.renderer.Present()
+}
@@ -0,0 +1,168 @@
+package ui2d
+
+import (
+	"experiments/experiments/RPG/game"
+	"testing"
+)
+
+type fakeView struct {
+	id        uint32
+	levelChan chan *game.Snapshot
+	destroyed bool
+}
+
+func (v *fakeView) windowID() uint32            { return v.id }
+func (v *fakeView) levels() chan *game.Snapshot { return v.levelChan }
+func (v *fakeView) frame() error                { return nil }
+func (v *fakeView) destroy()                    { v.destroyed = true }
+
+func TestViews(t *testing.T) {
+	var (
+		u    = &ui{inputChan: make(chan *game.Input, 10)}
+		a, b = &fakeView{id: 1, levelChan: game.NewLevelChan()}, &fakeView{id: 2, levelChan: game.NewLevelChan()}
+	)
+	var expect = func(typ game.InputType, v *fakeView) {
+		t.Helper()
+		select {
+		case input := <-u.inputChan:
+			if input.Type != typ || input.LevelChannel != v.levelChan {
+				t.Errorf("input %+v, want %v for view %d", input, typ, v.id)
+			}
+		default:
+			t.Errorf("no input, want %v for view %d", typ, v.id)
+		}
+	}
+	u.openView(a)
+	expect(game.OpenWindow, a)
+	u.openView(b)
+	expect(game.OpenWindow, b)
+	if v, ok := u.viewByID(2); !ok || v != b {
+		t.Errorf("window 2 is %v %v", v, ok)
+	}
+
+	u.closeView(a)
+	expect(game.CloseWindow, a)
+	if !a.destroyed || b.destroyed || len(u.views) != 1 || u.views[0] != b {
+		t.Errorf("after closing a: views %v, a destroyed %v, b destroyed %v", u.views, a.destroyed, b.destroyed)
+	}
+	if _, ok := u.viewByID(1); ok {
+		t.Error("found the closed window")
+	}
+	if _, ok := u.viewByID(3); ok {
+		t.Error("found a window that was never opened")
+	}
+}
+
+// sized is a w by h level with the player at 0,0 and a rat, ID 1, at 1,0.
+// visible says whether the player sees all of it or none.
+func sized(t *testing.T, w, h int, visible bool) *game.Snapshot {
+	t.Helper()
+	var d = game.Delta{Version: 1, Full: true}
+	d.Monsters = []game.Actor{{Character: game.Character{Entity: game.Entity{game.Position{1, 0}, "Rat", 'R'}}, ID: 1}}
+	for y := 0; y < h; y++ {
+		var row = make([]game.Tile, w)
+		for x := range row {
+			row[x] = game.Tile{Title: game.DirtFloor, Explored: visible, Visible: visible}
+		}
+		d.Map = append(d.Map, row)
+	}
+	s, err := d.Apply(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestSpectate checks the clicks that don't open a window.
+func TestSpectate(t *testing.T) {
+	var u = &ui{inputChan: make(chan *game.Input, 10)}
+	for _, test := range []struct {
+		name  string
+		drawn *game.Snapshot
+		pos   game.Position
+	}{
+		{"nothing drawn", nil, game.Position{1, 0}},
+		{"out of sight", sized(t, 3, 3, false), game.Position{1, 0}},
+		{"no one there", sized(t, 3, 3, true), game.Position{2, 2}},
+		{"off the map", sized(t, 3, 3, true), game.Position{5, 0}},
+	} {
+		u.drawn = test.drawn
+		if err := u.spectate(test.pos); err != nil || len(u.views) != 0 || len(u.inputChan) != 0 {
+			t.Errorf("%s: err %v, views %v", test.name, err, u.views)
+		}
+	}
+}
+
+func TestCameraTarget(t *testing.T) {
+	var (
+		level = sized(t, 3, 3, true)
+		u     = &ui{}
+	)
+	if pos := u.cameraTarget(level); pos != (game.Position{0, 0}) {
+		t.Errorf("the player's window looks at %v", pos)
+	}
+	u.follow = 1
+	if pos := u.cameraTarget(level); pos != (game.Position{1, 0}) {
+		t.Errorf("the rat's window looks at %v", pos)
+	}
+	// the rat is gone, the camera stays where it was
+	level.Monsters = nil
+	u.cameraSet, u.centerX, u.centerY = true, 2, 2
+	if pos := u.cameraTarget(level); pos != (game.Position{2, 2}) {
+		t.Errorf("looks at %v once the rat is gone", pos)
+	}
+	u.cameraSet = false
+	if pos := u.cameraTarget(level); pos != (game.Position{0, 0}) {
+		t.Errorf("looks at %v without a camera, want the player", pos)
+	}
+
+	// a spectator sees everything, the player what they've seen
+	var fog = game.Tile{Title: game.DirtFloor, Explored: true}
+	if !u.explored(game.Tile{}) || !u.visible(fog) {
+		t.Error("the spectator doesn't see everything")
+	}
+	u.follow = 0
+	if u.explored(game.Tile{}) || !u.explored(fog) || u.visible(fog) {
+		t.Error("the player sees past their fog")
+	}
+}
+
+func TestMinimap(t *testing.T) {
+	var m = &minimap{levelChan: game.NewLevelChan(), width: 320, height: 240}
+	m.update()
+	if m.level != nil {
+		t.Fatal("a level out of nowhere")
+	}
+	var first, second = sized(t, 3, 3, true), sized(t, 3, 3, true)
+	m.levelChan <- first
+	m.update()
+	m.update()
+	if m.level != first {
+		t.Error("didn't keep the level it was sent")
+	}
+	m.levelChan <- second
+	m.update()
+	if m.level != second {
+		t.Error("didn't take the newer level")
+	}
+	close(m.levelChan)
+	m.update()
+	if m.level != second {
+		t.Error("lost the level when the game closed the view")
+	}
+
+	var tests = []struct {
+		w, h            int
+		cell, left, top int32
+	}{
+		{80, 20, 4, 0, 80},
+		{10, 10, 24, 40, 0},
+		{320, 240, 1, 0, 0},
+		{1000, 10, 1, -340, 115},
+	}
+	for _, test := range tests {
+		if cell, left, top := m.layout(sized(t, test.w, test.h, false)); cell != test.cell || left != test.left || top != test.top {
+			t.Errorf("%dx%d: cell %d at %d,%d, want %d at %d,%d", test.w, test.h, cell, left, top, test.cell, test.left, test.top)
+		}
+	}
+}
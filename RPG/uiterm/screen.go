@@ -0,0 +1,79 @@
+package uiterm
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// cell is a character on the screen and its SGR color, e.g. "1;31".
+type cell struct {
+	r     rune
+	color string
+}
+
+// screen is drawn into in full every frame but only writes the cells
+// that differ from what the terminal shows, which keeps a turn to a few
+// bytes over SSH.
+type screen struct {
+	rows, cols int
+	cells      []cell
+	shown      []cell
+}
+
+// resize clears the screen and forgets what the terminal shows.
+func (s *screen) resize(rows, cols int) {
+	s.rows, s.cols = rows, cols
+	s.cells = make([]cell, rows*cols)
+	s.shown = make([]cell, rows*cols)
+	for i := range s.shown {
+		// nothing drawn matches, everything is written once
+		s.shown[i].r = -1
+	}
+}
+
+func (s *screen) clear() {
+	for i := range s.cells {
+		s.cells[i] = cell{' ', ""}
+	}
+}
+
+func (s *screen) set(x, y int, r rune, color string) {
+	if x >= 0 && y >= 0 && x < s.cols && y < s.rows {
+		s.cells[y*s.cols+x] = cell{r, color}
+	}
+}
+
+// text writes str from x, y on, cut off at the edge.
+func (s *screen) text(x, y int, str, color string) {
+	for _, r := range str {
+		s.set(x, y, r, color)
+		x++
+	}
+}
+
+// flush writes the changed cells to w, moving the cursor only where it
+// has to.
+func (s *screen) flush(w *bufio.Writer) error {
+	var (
+		color            = "-"
+		cursorX, cursorY = -1, -1 // where the terminal's cursor is
+	)
+	for i, c := range s.cells {
+		if c == s.shown[i] {
+			continue
+		}
+		var x, y = i % s.cols, i / s.cols
+		if cursorX != x || cursorY != y {
+			fmt.Fprintf(w, "\x1b[%d;%dH", y+1, x+1)
+		}
+		if c.color != color {
+			fmt.Fprintf(w, "\x1b[0;%sm", c.color)
+			color = c.color
+		}
+		w.WriteRune(c.r)
+		s.shown[i] = c
+		cursorX, cursorY = x+1, y
+	}
+	w.WriteString("\x1b[0m")
+	return w.Flush()
+}
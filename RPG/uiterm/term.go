@@ -0,0 +1,116 @@
+package uiterm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// rawMode puts the terminal on in into raw mode without echo and returns
+// what puts it back. It runs stty rather than making the ioctls, which
+// differ from one unix to the next. It fails if in isn't a terminal.
+func rawMode(in *os.File) (restore func(), err error) {
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(in, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(in, strings.TrimSpace(saved))
+	}, nil
+}
+
+func stty(in *os.File, args ...string) (string, error) {
+	var cmd = exec.Command("stty", args...)
+	cmd.Stdin = in
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// termSize is the rows and columns of the terminal on in, 24x80 if stty
+// can't tell.
+func termSize(in *os.File) (rows, cols int) {
+	if out, err := stty(in, "size"); err == nil {
+		if _, err := fmt.Sscan(out, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// readKeys sends the keys read from r until it fails, then closes the
+// channel.
+func readKeys(r io.Reader) <-chan string {
+	var keys = make(chan string)
+	go func() {
+		defer close(keys)
+		var buf = make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+var arrowKeys = map[byte]string{
+	'A': "up",
+	'B': "down",
+	'C': "right",
+	'D': "left",
+}
+
+// parseKeys splits what a read returned into keys: the arrows are "up",
+// "down", "left" and "right", a lone escape "esc", control characters
+// "ctrl-c" and so on, "enter", and anything else the character itself.
+// Escape sequences for other keys are dropped.
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		switch {
+		case len(b) >= 3 && b[0] == 0x1b && b[1] == 'O':
+			// SS3, the arrows in application mode
+			if key, ok := arrowKeys[b[2]]; ok {
+				keys = append(keys, key)
+			}
+			b = b[3:]
+		case len(b) >= 2 && b[0] == 0x1b && b[1] == '[':
+			// CSI: parameters and intermediates up to a final byte
+			var end = 2
+			for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+				end++
+			}
+			if end == len(b) {
+				return keys
+			}
+			if key, ok := arrowKeys[b[end]]; ok {
+				keys = append(keys, key)
+			}
+			b = b[end+1:]
+		case b[0] == 0x1b:
+			keys = append(keys, "esc")
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, "enter")
+			b = b[1:]
+		case b[0] < 0x20:
+			keys = append(keys, "ctrl-"+strings.ToLower(string('@'+rune(b[0]))))
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, string(r))
+			b = b[size:]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,316 @@
+// Package uiterm plays the RPG in a terminal: the map in ANSI colors, a
+// status line, the player's stats and inventory and the event log. It
+// talks to the game like ui2d does, over InputChan and a level channel,
+// and needs nothing but stty.
+//
+// The keys are those of ui2d's default bindings: the arrows or hjkl to
+// move, yubn diagonally if the game allows it, . to wait, s to explore,
+// g or , to pick up, d to drop the last item, 1-9 to use one, r to
+// restart once dead and q or escape to quit. Ctrl-L redraws after the
+// terminal is resized.
+//
+// If stdin isn't a terminal the keys are read as they come, so that a
+// game can be scripted: echo sssssq | rpgterm
+package uiterm
+
+import (
+	"bufio"
+	"experiments/experiments/RPG/game"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	logLines   = 5  // of the event log under the map
+	panelWidth = 26 // of the stats and inventory right of the map
+)
+
+// SGR colors
+const (
+	fogColor     = "90"
+	playerColor  = "1;97"
+	partyColor   = "1;96"
+	monsterColor = "1;31"
+	itemColor    = "1;35"
+	statusColor  = "7"
+	deathColor   = "1;97;41"
+)
+
+var tileColors = map[game.Title]string{
+	game.StoneWall: "37",
+	game.DirtFloor: "33",
+	game.CloseDoor: "1;33",
+	game.OpenDoor:  "1;33",
+	game.UpStair:   "1;93",
+	game.DownStair: "1;93",
+	game.Mud:       "33",
+	game.Water:     "1;34",
+}
+
+var keyInputs = map[string]game.InputType{
+	"up":    game.Up,
+	"k":     game.Up,
+	"down":  game.Down,
+	"j":     game.Down,
+	"left":  game.Left,
+	"h":     game.Left,
+	"right": game.Right,
+	"l":     game.Right,
+	"y":     game.UpLeft,
+	"u":     game.UpRight,
+	"b":     game.DownLeft,
+	"n":     game.DownRight,
+	".":     game.None,
+	"s":     game.Explore,
+	"g":     game.Pickup,
+	",":     game.Pickup,
+}
+
+type ui struct {
+	inputChan chan *game.Input
+	levelChan chan *game.Snapshot
+	in        *os.File
+	out       *bufio.Writer
+	screen    screen
+	level     *game.Snapshot
+}
+
+// NewUI plays on the level snapshots sent down levelChan, reading keys
+// from in and drawing to out, usually os.Stdin and os.Stdout.
+func NewUI(inputChan chan *game.Input, levelChan chan *game.Snapshot, in *os.File, out io.Writer) *ui {
+	return &ui{
+		inputChan: inputChan,
+		levelChan: levelChan,
+		in:        in,
+		out:       bufio.NewWriter(out),
+	}
+}
+
+// Run plays until the player quits or the game closes the level channel.
+// If the screen can't be written to it quits the game and returns why,
+// the terminal is put back either way.
+func (ui *ui) Run() error {
+	if restore, err := rawMode(ui.in); err == nil {
+		defer restore()
+	}
+	// the alternate screen, without a cursor, is left as it was found
+	ui.out.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		ui.out.WriteString("\x1b[0m\x1b[?25h\x1b[?1049l")
+		ui.out.Flush()
+	}()
+	ui.resize()
+
+	var keys = readKeys(ui.in)
+	for {
+		select {
+		case level, ok := <-ui.levelChan:
+			if !ok {
+				return nil
+			}
+			ui.level = level
+			if err := ui.draw(); err != nil {
+				return ui.quit(err)
+			}
+		case key, ok := <-keys:
+			if !ok {
+				// end of a script
+				return ui.quit(nil)
+			}
+			switch key {
+			case "q", "esc", "ctrl-c":
+				return ui.quit(nil)
+			case "ctrl-l":
+				ui.resize()
+				if err := ui.draw(); err != nil {
+					return ui.quit(err)
+				}
+				continue
+			}
+			if ui.level != nil && ui.level.Player.Hitpoints <= 0 {
+				// the death screen only restarts or quits
+				if key == "r" {
+					ui.inputChan <- &game.Input{Type: game.Restart, LevelChannel: ui.levelChan}
+				}
+				continue
+			}
+			if input, ok := ui.keyInput(key); ok {
+				input.LevelChannel = ui.levelChan
+				ui.inputChan <- &input
+			}
+		}
+	}
+}
+
+// quit ends the game and returns err.
+func (ui *ui) quit(err error) error {
+	ui.inputChan <- &game.Input{Type: game.QuitGame, LevelChannel: ui.levelChan}
+	return err
+}
+
+// keyInput is the game.Input a key stands for, if any.
+func (ui *ui) keyInput(key string) (game.Input, bool) {
+	if t, ok := keyInputs[key]; ok {
+		return game.Input{Type: t}, true
+	}
+	switch {
+	case key == "d":
+		// drops the last item picked up
+		var input = game.Input{Type: game.Drop, Item: -1}
+		if ui.level != nil {
+			input.Item = len(ui.level.Player.Inventory) - 1
+		}
+		return input, true
+	case len(key) == 1 && key[0] >= '1' && key[0] <= '9':
+		return game.Input{Type: game.Use, Item: int(key[0] - '1')}, true
+	}
+	return game.Input{}, false
+}
+
+func (ui *ui) resize() {
+	ui.screen.resize(termSize(ui.in))
+	ui.out.WriteString("\x1b[2J")
+}
+
+func (ui *ui) draw() error {
+	if ui.level == nil {
+		return nil
+	}
+	var (
+		level = ui.level
+		s     = &ui.screen
+		viewW = s.cols
+		viewH = s.rows - 1 - logLines
+	)
+	if s.cols >= 2*panelWidth {
+		viewW -= panelWidth
+	}
+	if viewH < 1 {
+		viewH = 1
+	}
+	s.clear()
+
+	var (
+		left = scroll(level.Player.X, viewW, level.Width())
+		top  = scroll(level.Player.Y, viewH, level.Height())
+	)
+	for y := 0; y < viewH; y++ {
+		for x := 0; x < viewW; x++ {
+			r, color := glyph(level, game.Position{left + x, top + y})
+			s.set(x, y, r, color)
+		}
+	}
+	if viewW < s.cols {
+		ui.drawPanel(viewW+1, &level.Player)
+	}
+	ui.drawStatus(viewH)
+	for i, message := range level.Messages(logLines) {
+		s.text(0, viewH+1+i, message, "")
+	}
+
+	return s.flush(ui.out)
+}
+
+// scroll is the first column or row of a map size long to show in a
+// view n long so that p is in the middle, without going past the edges.
+func scroll(p, n, size int) int {
+	if size <= n {
+		return 0
+	}
+	var first = p - n/2
+	if first < 0 {
+		first = 0
+	}
+	if first > size-n {
+		first = size - n
+	}
+	return first
+}
+
+// glyph is what a tile shows: the player, the rest of the party, a
+// monster in sight, the top item or the terrain, grayed out where it's
+// out of sight.
+func glyph(level *game.Snapshot, pos game.Position) (rune, string) {
+	var tile = level.Tile(pos)
+	if pos == level.Player.Position {
+		return level.Player.Rune, playerColor
+	}
+	if p, ok := level.Others[pos]; ok {
+		return p.Rune, partyColor
+	}
+	if !tile.Explored || tile.Title == game.Blank {
+		return ' ', ""
+	}
+	if m, ok := level.Monsters[pos]; ok && tile.Visible {
+		return m.Rune, monsterColor
+	}
+	var r, color = rune(tile.Title), tileColors[tile.Title]
+	if items := level.Items[pos]; len(items) > 0 {
+		r, color = items[len(items)-1].Rune, itemColor
+	}
+	if !tile.Visible {
+		color = fogColor
+	}
+	return r, color
+}
+
+type line struct {
+	text  string
+	color string
+}
+
+// drawPanel lists the player's stats and inventory from column x.
+func (ui *ui) drawPanel(x int, player *game.Player) {
+	var (
+		lo, hi  = player.GetDamageRange()
+		hpColor = ""
+	)
+	if player.Hitpoints*3 <= player.MaxHitpoints {
+		hpColor = monsterColor
+	}
+	var lines = []line{
+		{player.Name, ""},
+		{fmt.Sprintf("Level %d  XP %d", player.Level, player.Experience), ""},
+		{fmt.Sprintf("HP %d/%d", player.Hitpoints, player.MaxHitpoints), hpColor},
+		{fmt.Sprintf("Damage %d-%d", lo, hi), ""},
+		{fmt.Sprintf("Defense %d", player.GetDefense()), ""},
+	}
+	if player.Weapon != nil {
+		lines = append(lines, line{"Weapon: " + player.Weapon.Name, ""})
+	}
+	if player.Armor != nil {
+		lines = append(lines, line{"Armor: " + player.Armor.Name, ""})
+	}
+	lines = append(lines, line{})
+	for i, item := range player.Inventory {
+		lines = append(lines, line{fmt.Sprintf("%d %s", i+1, item.Name), itemColor})
+	}
+	for i, l := range lines {
+		ui.screen.text(x, i, l.text, l.color)
+	}
+}
+
+// drawStatus fills row y with where the player is, or how they died.
+func (ui *ui) drawStatus(y int) {
+	var (
+		level  = ui.level
+		status = fmt.Sprintf(" Depth %d  Turn %d   s explore  g pick up  d drop  1-9 use  q quit", level.Depth+1, level.Turn)
+		color  = statusColor
+	)
+	if level.Name != "" {
+		status = " " + level.Name + " " + status
+	}
+	if level.Player.Hitpoints <= 0 {
+		var cause = "Died"
+		if death, ok := level.PlayerDeath(); ok {
+			cause = fmt.Sprintf("Killed by %s on turn %d", death.Actor.Name, death.Turn)
+		}
+		status = fmt.Sprintf(" YOU DIED  %s, level %d, %d XP   r restart  q quit", cause, level.Player.Level, level.Player.Experience)
+		color = deathColor
+	}
+	for x := 0; x < ui.screen.cols; x++ {
+		ui.screen.set(x, y, ' ', color)
+	}
+	ui.screen.text(0, y, status, color)
+}
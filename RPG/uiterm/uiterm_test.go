@@ -0,0 +1,239 @@
+package uiterm
+
+import (
+	"bytes"
+	"experiments/experiments/RPG/game"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testLevel is a snapshot of rows: @ is the player on floor, R a rat, !
+// a potion, f floor out of sight and a space unexplored.
+func testLevel(t *testing.T, rows ...string) *game.Snapshot {
+	t.Helper()
+	var d = game.Delta{Version: 1, Full: true, Turn: 3, Player: game.Player{Character: game.Character{
+		Entity:    game.Entity{Name: "GoMen", Rune: '@'},
+		Hitpoints: 20, MaxHitpoints: 20, Strength: 2,
+	}, Level: 1}}
+	for y, row := range rows {
+		var tiles []game.Tile
+		for x, r := range row {
+			var (
+				pos  = game.Position{x, y}
+				tile = game.Tile{Title: game.Title(r), Explored: true, Visible: true}
+			)
+			switch r {
+			case '@':
+				d.Player.Position = pos
+				tile.Title = game.DirtFloor
+			case 'R':
+				d.Monsters = append(d.Monsters, game.Actor{Character: game.Character{Entity: game.Entity{pos, "Rat", 'R'}, Hitpoints: 5}, ID: 1})
+				tile.Title = game.DirtFloor
+			case '!':
+				d.Items = append(d.Items, *game.NewHealthPotion(pos))
+				tile.Title = game.DirtFloor
+			case 'f':
+				tile = game.Tile{Title: game.DirtFloor, Explored: true}
+			case ' ':
+				tile = game.Tile{Title: game.Blank}
+			}
+			tiles = append(tiles, tile)
+		}
+		d.Map = append(d.Map, tiles)
+	}
+	s, err := d.Apply(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (s *screen) row(y int) string {
+	var row []rune
+	for _, c := range s.cells[y*s.cols : (y+1)*s.cols] {
+		row = append(row, c.r)
+	}
+	return strings.TrimRight(string(row), " ")
+}
+
+func (s *screen) color(x, y int) string {
+	return s.cells[y*s.cols+x].color
+}
+
+func TestDraw(t *testing.T) {
+	var (
+		out bytes.Buffer
+		ui  = NewUI(nil, nil, nil, &out)
+	)
+	ui.screen.resize(10, 40)
+	ui.level = testLevel(t, "#######", "#@.R!f#", "## ####")
+	if err := ui.draw(); err != nil {
+		t.Fatal(err)
+	}
+	var s = &ui.screen
+	for y, want := range []string{"#######", "#@.R!.#", "## ####", ""} {
+		if got := s.row(y); got != want {
+			t.Errorf("row %d is %q, want %q", y, got, want)
+		}
+	}
+	for _, c := range []struct {
+		x, y  int
+		color string
+	}{{1, 1, playerColor}, {3, 1, monsterColor}, {4, 1, itemColor}, {5, 1, fogColor}, {0, 0, tileColors[game.StoneWall]}} {
+		if got := s.color(c.x, c.y); got != c.color {
+			t.Errorf("color at %d,%d is %q, want %q", c.x, c.y, got, c.color)
+		}
+	}
+	// the status line is under the map, the log under that
+	if status := s.row(4); !strings.HasPrefix(status, " Depth 1  Turn 3") || s.color(0, 4) != statusColor {
+		t.Errorf("status %q", status)
+	}
+	if !strings.HasPrefix(out.String(), "\x1b[1;1H\x1b[0;37m#") {
+		t.Errorf("wrote %q", out.String())
+	}
+
+	// only what changed is written again
+	out.Reset()
+	if err := ui.draw(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "\x1b[0m" {
+		t.Errorf("redrew %q", out.String())
+	}
+	ui.level.Player.Hitpoints = 0
+	out.Reset()
+	if err := ui.draw(); err != nil {
+		t.Fatal(err)
+	}
+	if status := s.row(4); !strings.HasPrefix(status, " YOU DIED  Died, level 1") || s.color(0, 4) != deathColor {
+		t.Errorf("status %q when dead", status)
+	}
+	if strings.Contains(out.String(), "#") {
+		t.Errorf("redrew the map for the status line: %q", out.String())
+	}
+}
+
+func TestDrawPanel(t *testing.T) {
+	var ui = NewUI(nil, nil, nil, &bytes.Buffer{})
+	ui.screen.resize(16, 60)
+	ui.level = testLevel(t, "###", "#@#", "###")
+	ui.level.Player.Inventory = []*game.Item{game.NewSword(game.Position{})}
+	if err := ui.draw(); err != nil {
+		t.Fatal(err)
+	}
+	// the map gets what the panel leaves, 60-26 columns
+	for y, want := range map[int]string{0: "GoMen", 2: "HP 20/20", 6: "1 Sword"} {
+		if got := strings.TrimSpace(ui.screen.row(y)[3:]); got != want {
+			t.Errorf("panel row %d is %q, want %q", y, got, want)
+		}
+	}
+	if ui.screen.cells[35].r != 'G' {
+		t.Error("the panel doesn't start at column 35")
+	}
+}
+
+func TestScroll(t *testing.T) {
+	var tests = []struct{ p, n, size, first int }{
+		{5, 10, 8, 0},
+		{2, 10, 100, 0},
+		{50, 10, 100, 45},
+		{98, 10, 100, 90},
+	}
+	for _, test := range tests {
+		if first := scroll(test.p, test.n, test.size); first != test.first {
+			t.Errorf("scroll(%d, %d, %d) = %d, want %d", test.p, test.n, test.size, first, test.first)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	var tests = []struct {
+		in   string
+		keys []string
+	}{
+		{"hjkl", []string{"h", "j", "k", "l"}},
+		{"\x1b[A\x1b[B\x1bOC\x1b[1;5D", []string{"up", "down", "right", "left"}},
+		{"\x1b", []string{"esc"}},
+		{"\x1b[3~x", []string{"x"}},
+		{"\x03\x0c\r", []string{"ctrl-c", "ctrl-l", "enter"}},
+		{"é.", []string{"é", "."}},
+		{"\x1b[1;5", nil},
+	}
+	for _, test := range tests {
+		if keys := parseKeys([]byte(test.in)); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("%q is %q, want %q", test.in, keys, test.keys)
+		}
+	}
+}
+
+func TestKeyInput(t *testing.T) {
+	var ui = NewUI(nil, nil, nil, &bytes.Buffer{})
+	var tests = []struct {
+		key   string
+		input game.Input
+		ok    bool
+	}{
+		{"up", game.Input{Type: game.Up}, true},
+		{"l", game.Input{Type: game.Right}, true},
+		{"n", game.Input{Type: game.DownRight}, true},
+		{".", game.Input{Type: game.None}, true},
+		{",", game.Input{Type: game.Pickup}, true},
+		{"s", game.Input{Type: game.Explore}, true},
+		{"d", game.Input{Type: game.Drop, Item: -1}, true},
+		{"1", game.Input{Type: game.Use}, true},
+		{"9", game.Input{Type: game.Use, Item: 8}, true},
+		{"0", game.Input{}, false},
+		{"x", game.Input{}, false},
+	}
+	for _, test := range tests {
+		if input, ok := ui.keyInput(test.key); input != test.input || ok != test.ok {
+			t.Errorf("%q is %+v %v, want %+v %v", test.key, input, ok, test.input, test.ok)
+		}
+	}
+	// d drops what was picked up last
+	ui.level = testLevel(t, "@")
+	ui.level.Player.Inventory = []*game.Item{game.NewSword(game.Position{}), game.NewHealthPotion(game.Position{})}
+	if input, _ := ui.keyInput("d"); input.Item != 1 {
+		t.Errorf("d drops item %d, want 1", input.Item)
+	}
+}
+
+// TestRun scripts a game: the keys go in through a pipe, which isn't a
+// terminal, and come out as inputs for the ui's view.
+func TestRun(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var (
+		inputChan = make(chan *game.Input)
+		view      = game.NewLevelChan()
+		ui        = NewUI(inputChan, view, r, &bytes.Buffer{})
+		done      = make(chan error)
+	)
+	go func() { done <- ui.Run() }()
+	w.WriteString("l\x1b[A.x2q")
+	w.Close()
+
+	var got []game.Input
+	for input := range inputChan {
+		if input.LevelChannel != view {
+			t.Errorf("%+v isn't for the ui's view", input)
+		}
+		input.LevelChannel = nil
+		got = append(got, *input)
+		if input.Type == game.QuitGame {
+			break
+		}
+	}
+	var want = []game.Input{{Type: game.Right}, {Type: game.Up}, {Type: game.None}, {Type: game.Use, Item: 1}, {Type: game.QuitGame}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inputs %+v, want %+v", got, want)
+	}
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package assets finds the files a program needs at run time. Names are
+// slash separated and relative to an asset root; a Loader tries each of
+// its roots on disk in turn and then the files compiled into the binary,
+// so a build runs from anywhere while a checkout can still edit its maps
+// and art in place.
+package assets
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Loader struct {
+	// Roots are the directories searched, in order
+	Roots []string
+	// Embedded are the files compiled in, usually with go:embed and put
+	// together with Mounts. May be nil.
+	Embedded fs.FS
+}
+
+// NotFoundError is returned for a name that is neither under a root nor,
+// if those were looked at, embedded.
+type NotFoundError struct {
+	Name     string
+	Roots    []string
+	Embedded bool
+}
+
+func (e *NotFoundError) Error() string {
+	var where = strings.Join(e.Roots, ", ")
+	if e.Embedded {
+		where += " or embedded"
+	}
+	return fmt.Sprintf("assets: %s not found in %s", e.Name, where)
+}
+
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+func NewLoader(embedded fs.FS, roots ...string) *Loader {
+	return &Loader{Roots: roots, Embedded: embedded}
+}
+
+// Mounts puts file systems under directories, the way the packages that
+// embed them sit in the source tree: with Mounts{"game": game.Files} the
+// file game.Files has as maps/level_1.map is game/maps/level_1.map.
+type Mounts map[string]fs.FS
+
+func (m Mounts) Open(name string) (fs.File, error) {
+	if fs.ValidPath(name) {
+		if i := strings.Index(name, "/"); i > 0 {
+			if fsys, ok := m[name[:i]]; ok {
+				return fsys.Open(name[i+1:])
+			}
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Roots is the usual search path: the directory in the environment
+// variable env if it is set, the working directory, the directory of the
+// executable, and importPath in every GOPATH for go run.
+func Roots(env, importPath string) []string {
+	var roots []string
+	if dir := os.Getenv(env); dir != "" {
+		roots = append(roots, dir)
+	}
+	if dir, err := os.Getwd(); err == nil {
+		roots = append(roots, dir)
+	}
+	if exe, err := os.Executable(); err == nil {
+		roots = append(roots, filepath.Dir(exe))
+	}
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		roots = append(roots, filepath.Join(gopath, "src", filepath.FromSlash(importPath)))
+	}
+	return roots
+}
+
+// Path is where name is on disk. Embedded files have no path.
+func (l *Loader) Path(name string) (string, error) {
+	for _, root := range l.Roots {
+		var path = filepath.Join(root, filepath.FromSlash(name))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", &NotFoundError{name, l.Roots, false}
+}
+
+// Open returns the file on disk if there is one, the embedded copy
+// otherwise.
+func (l *Loader) Open(name string) (io.ReadCloser, error) {
+	if path, err := l.Path(name); err == nil {
+		return os.Open(path)
+	}
+	if l.Embedded != nil {
+		if file, err := l.Embedded.Open(name); err == nil {
+			if info, err := file.Stat(); err == nil && !info.IsDir() {
+				return file, nil
+			}
+			file.Close()
+		}
+	}
+	return nil, &NotFoundError{name, l.Roots, l.Embedded != nil}
+}
+
+func (l *Loader) ReadFile(name string) ([]byte, error) {
+	file, err := l.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
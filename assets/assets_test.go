@@ -0,0 +1,43 @@
+package assets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoader(t *testing.T) {
+	var root = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "game"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "game", "a.txt"), []byte("disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var loader = NewLoader(Mounts{"game": fstest.MapFS{
+		"a.txt":     {Data: []byte("embedded a")},
+		"sub/b.txt": {Data: []byte("embedded b")},
+	}}, root)
+
+	var tests = []struct {
+		name string
+		want string
+	}{
+		{"game/a.txt", "disk"},
+		{"game/sub/b.txt", "embedded b"},
+		{"game/c.txt", ""},
+		{"game/sub", ""},
+		{"ui2d/a.txt", ""},
+	}
+	for _, test := range tests {
+		data, err := loader.ReadFile(test.name)
+		switch {
+		case test.want == "" && !IsNotFound(err):
+			t.Errorf("%s: %q, %v, want not found", test.name, data, err)
+		case test.want != "" && string(data) != test.want:
+			t.Errorf("%s: %q, %v, want %q", test.name, data, err, test.want)
+		}
+	}
+}
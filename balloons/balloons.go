@@ -1,11 +1,11 @@
 package main
 
 import (
+	"experiments/experiments/assets"
 	"experiments/noise"
 	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
 	"image/png"
-	"os"
 	"time"
 )
 
@@ -14,6 +14,10 @@ const (
 	windowHeight = 600
 )
 
+// the pngs are looked up under $BALLOONS_ASSETS, the working directory,
+// next to the executable and in the source tree
+var loader = assets.NewLoader(nil, assets.Roots("BALLOONS_ASSETS", "experiments/experiments/balloons")...)
+
 type texture struct {
 	position
 	pixels []byte
@@ -175,15 +179,15 @@ func (t *texture) drawAlpha( pixels []byte)  {
 func loadBalloons() []texture {
 
 	balloonNames := []string {
-		"C:/Users/xpoc_/go/src/experiments/balloons/balloon_red.png",
-		"C:/Users/xpoc_/go/src/experiments/balloons/balloon_green.png",
-		"C:/Users/xpoc_/go/src/experiments/balloons/balloon_blue.png"}
+		"balloon_red.png",
+		"balloon_green.png",
+		"balloon_blue.png"}
 
 	balloonTextures := make([]texture, len(balloonNames))
 
 	for i, name := range balloonNames {
 
-		file, err := os.Open(name)
+		file, err := loader.Open(name)
 		if err != nil {
 			panic(err)
 		}
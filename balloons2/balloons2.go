@@ -1,6 +1,7 @@
 package main
 
 import (
+	"experiments/experiments/assets"
 	"experiments/experiments/noise"
 	"experiments/experiments/vec3"
 	"github.com/veandco/go-sdl2/sdl"
@@ -8,7 +9,6 @@ import (
 	"log"
 	"math"
 	"math/rand"
-	"os"
 	"sort"
 	"time"
 )
@@ -19,6 +19,10 @@ const (
 	windowDepth  = 100
 )
 
+// the art and the sound are looked up under $BALLOONS_ASSETS, the working
+// directory, next to the executable and in the source tree
+var loader = assets.NewLoader(nil, assets.Roots("BALLOONS_ASSETS", "experiments/experiments/balloons2")...)
+
 type audioState struct {
 	explosionBytes []byte
 	deviceId       sdl.AudioDeviceID
@@ -226,7 +230,7 @@ func pixelsToTexture(renderer *sdl.Renderer, pixels []byte, w, h int) *sdl.Textu
 
 func imgFileToTexture(renderer *sdl.Renderer, fileName string) *sdl.Texture {
 
-	file, err := os.Open(fileName)
+	file, err := loader.Open(fileName)
 	if err != nil {
 		panic(err)
 	}
@@ -266,12 +270,12 @@ func imgFileToTexture(renderer *sdl.Renderer, fileName string) *sdl.Texture {
 
 func loadBalloons(renderer *sdl.Renderer, numBalloons int) []*balloon {
 
-	explosionTexture := imgFileToTexture(renderer, "C:/Users/xpoc_/go/src/experiments/balloons/explosion.png")
+	explosionTexture := imgFileToTexture(renderer, "explosion.png")
 
 	balloonNames := []string{
-		"C:/Users/xpoc_/go/src/experiments/balloons/balloon_red.png",
-		"C:/Users/xpoc_/go/src/experiments/balloons/balloon_green.png",
-		"C:/Users/xpoc_/go/src/experiments/balloons/balloon_blue.png"}
+		"balloon_red.png",
+		"balloon_green.png",
+		"balloon_blue.png"}
 
 	balloonTextures := make([]*sdl.Texture, len(balloonNames))
 
@@ -365,8 +369,12 @@ func main() {
 	}
 	defer renderer.Destroy()
 
+	wavPath, err := loader.Path("explode.wav")
+	if err != nil {
+		panic(err)
+	}
 	var audioSpec *sdl.AudioSpec
-	explosionBytes, audioSpec := sdl.LoadWAV("C:/Users/xpoc_/go/src/experiments/balloons/explode.wav")
+	explosionBytes, audioSpec := sdl.LoadWAV(wavPath)
 	audioId, err := sdl.OpenAudioDevice("", false, audioSpec, nil, 0)
 	if err != nil {
 		panic(err)